@@ -2,24 +2,24 @@ package main
 
 import (
 	"context"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/rs/zerolog/log"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"job_search_platform/internal/gateway_mrc/jobs"
 	"job_search_platform/internal/gateway_mrc/server"
+	"job_search_platform/internal/gateway_mrc/sessions"
+	"job_search_platform/internal/gateway_mrc/usecases"
+	"job_search_platform/pkg/app"
 	config2 "job_search_platform/pkg/config"
 	"job_search_platform/pkg/database"
+	"job_search_platform/pkg/health"
 	logger2 "job_search_platform/pkg/logger"
+	"job_search_platform/pkg/metrics"
+	"job_search_platform/pkg/tracing"
 	"os"
-	"os/signal"
-	"syscall"
 )
 
-var interruptSignals = []os.Signal{
-	os.Interrupt,
-	syscall.SIGTERM,
-	syscall.SIGINT,
-}
-
 func main() {
 	var configPath string
 	if _, err := os.Stat("/app"); err == nil {
@@ -27,19 +27,31 @@ func main() {
 	} else {
 		configPath = "../.."
 	}
-	config, err := config2.LoadConfig(configPath, "gateway_mrc")
+	config, err := config2.LoadGatewayMrc(configPath)
+	// `gateway_mrc config print [--redacted]` shows what the service would start with
+	if code, ok := config2.RunCommand(os.Args[1:], &config, err); ok {
+		os.Exit(code)
+	}
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot load config")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	logger, file := logger2.ConfigureLogger(config.Environment)
+	logger, file := logger2.ConfigureLogger(config.Common)
 	defer file.Close()
-	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
-	defer stop()
-	connPool, err := pgxpool.New(ctx, config.PostgresSource)
-	defer connPool.Close()
+	application := app.New("gateway_mrc", config.ShutdownTimeout, logger)
+	ctx := context.Background()
+
+	shutdownTracing, err := tracing.Init(ctx, config.Common, "gateway_mrc")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("cannot init tracing")
+	}
+	application.OnStop("tracing", shutdownTracing)
+
+	connPool, err := database.NewPool(ctx, config.PostgresSource)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("cannot connect to db")
 	}
+	application.OnStop("postgres pool", app.Wait(connPool.Close))
 	err = connPool.Ping(ctx)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("cannot connect to db")
@@ -49,8 +61,44 @@ func main() {
 		logger.Fatal().Err(err).Msg("cannot run migration")
 	}
 	store := db.NewStore(connPool)
-	err = server.RunGinServer(config, store, logger)
+	redisClient := redis.NewClient(&redis.Options{Addr: config.RedisAddress})
+	application.OnStop("redis client", func(context.Context) error { return redisClient.Close() })
+	sessionStore, err := sessions.NewSessionStore(config.SessionStore, redisClient, store)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("cannot create session store")
+	}
+	sessionsUsecase := usecases.NewSessionsUsecase(sessionStore, config)
+
+	redisOpt := asynq.RedisClientOpt{Addr: config.RedisAddress}
+	metrics.RegisterPgxPool(connPool)
+	metrics.RegisterQueues(redisOpt, jobs.QueueGateway)
+	if metricsServer := metrics.NewServer(config.MetricsAddress); metricsServer != nil {
+		application.Add(app.HttpServer("metrics server", metricsServer))
+	}
+
+	sessionJobs, err := jobs.NewSessionJobs(config, redisOpt, &sessionsUsecase, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("cannot create session jobs")
+	}
+	application.Add(app.Hook{
+		Name:    "session jobs",
+		OnStart: func(context.Context) error { return sessionJobs.Start() },
+		OnStop:  app.Wait(sessionJobs.Shutdown),
+	})
+
+	readiness := health.New(config.HealthCheckTimeout)
+	readiness.Add("postgres", health.PgxPool(connPool))
+	readiness.Add("redis", health.Redis(redisClient))
+	httpServer, err := server.NewServer(config, store, redisClient, sessionsUsecase, readiness, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("cannot create gin server")
+	}
+	application.Add(httpServer.Hook())
+
+	err = application.Run(ctx)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("cannot run gin server")
+		logger.Error().Err(err).Msg("application stopped with an error")
+		file.Close()
+		os.Exit(1)
 	}
 }
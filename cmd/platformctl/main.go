@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// errUsage makes run print the usage of the command and exit with 2
+var errUsage = errors.New("usage")
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, configPath string, args []string) error
+}
+
+var commands = []command{
+	{"migrate", "migrate <users_mrc|gateway_mrc> up | down [n] | to <version> | force <version> | status",
+		"run the migrations of a service, the services only migrate up on start", runMigrate},
+	{"seed", "seed", "create the groups and permissions, running it again changes nothing", runSeed},
+	{"create-admin", "create-admin --email <email> [--password <password>] [--first-name <name>] [--last-name <name>] [--lang-code <code>]",
+		"create a user in the administrators group, the password is read from stdin when not given", runCreateAdmin},
+	{"reset-password", "reset-password --email <email> [--password <password>]",
+		"set a new password, the policy and the password history apply", runResetPassword},
+	{"ban-user", "ban-user --email <email> [--unban]",
+		"block the sign-in and the token refresh of the user", runBanUser},
+	{"list-sessions", "list-sessions [--limit <n>] [--session <fingerprint>]",
+		"list the active gateway sessions by the fingerprint of the logs, the last --limit ones are searched", runListSessions},
+}
+
+// platformctl is the admin tool of the platform, it reads the configuration of the services
+// from app.env and the environment the same way the services do
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("platformctl", flag.ContinueOnError)
+	flags.Usage = usage
+	configPath := flags.String("config", "../..", "directory of app.env")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		usage()
+		return 2
+	}
+	name := flags.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err := cmd.run(ctx, *configPath, flags.Args()[1:])
+		switch {
+		case errors.Is(err, errUsage):
+			fmt.Fprintln(os.Stderr, "usage: platformctl", cmd.usage)
+			return 2
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case err != nil:
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+	usage()
+	return 2
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: platformctl [--config <dir>] <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %s\n    \t%s\n", cmd.usage, cmd.summary)
+	}
+}
+
+// parseFlags parses the flags of a command, the positional arguments are not accepted
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	config2 "job_search_platform/pkg/config"
+	"job_search_platform/pkg/database"
+	"strconv"
+)
+
+// migrationSource returns the database and the migration files of the service
+func migrationSource(configPath, service string) (config2.Postgres, error) {
+	switch service {
+	case "users_mrc":
+		config, err := config2.LoadUsersMrc(configPath)
+		return config.Postgres, err
+	case "gateway_mrc":
+		config, err := config2.LoadGatewayMrc(configPath)
+		return config.Postgres, err
+	}
+	return config2.Postgres{}, fmt.Errorf("unknown service %q: %w", service, errUsage)
+}
+
+func runMigrate(_ context.Context, configPath string, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	postgres, err := migrationSource(configPath, args[0])
+	if err != nil {
+		return err
+	}
+	migrator, err := database.NewMigrator(postgres.MigrationURL, postgres.PostgresSource)
+	if err != nil {
+		return fmt.Errorf("cannot open migrations: %w", err)
+	}
+	defer migrator.Close()
+
+	action, params := args[1], args[2:]
+	switch {
+	case action == "up" && len(params) == 0:
+		err = migrator.Up()
+	case action == "down" && len(params) <= 1:
+		// без аргумента откатывается одна миграция, а не вся схема
+		steps := 1
+		if len(params) == 1 {
+			if steps, err = strconv.Atoi(params[0]); err != nil {
+				return errUsage
+			}
+		}
+		err = migrator.Down(steps)
+	case action == "to" && len(params) == 1:
+		version, parseErr := strconv.ParseUint(params[0], 10, 64)
+		if parseErr != nil {
+			return errUsage
+		}
+		err = migrator.To(uint(version))
+	case action == "force" && len(params) == 1:
+		version, parseErr := strconv.Atoi(params[0])
+		if parseErr != nil {
+			return errUsage
+		}
+		err = migrator.Force(version)
+	case action == "status" && len(params) == 0:
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return fmt.Errorf("migrate %s: %w", action, err)
+	}
+	return printMigrationStatus(migrator, args[0])
+}
+
+func printMigrationStatus(migrator *database.Migrator, service string) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return fmt.Errorf("cannot read migration status: %w", err)
+	}
+	dirty := ""
+	if status.Dirty {
+		dirty = " (dirty: fix the schema and run `migrate " + service + " force <version>`)"
+	}
+	fmt.Printf("%s: version %d of %d%s\n", service, status.Version, status.Latest, dirty)
+	if len(status.Pending) > 0 {
+		fmt.Printf("pending: %v\n", status.Pending)
+	}
+	return nil
+}
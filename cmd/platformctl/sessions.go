@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"job_search_platform/internal/gateway_mrc/sessions"
+	config2 "job_search_platform/pkg/config"
+	"job_search_platform/pkg/database"
+	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/logger"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// runListSessions prints the sessions by the fingerprint the gateway logs, the id is the cookie value
+// and must not end up in terminals and CI logs. --session finds the session of a log line.
+func runListSessions(ctx context.Context, configPath string, args []string) error {
+	flags := flag.NewFlagSet("list-sessions", flag.ContinueOnError)
+	limit := flags.Int("limit", 100, "maximum number of sessions")
+	fingerprint := flags.String("session", "", "show only the session with the fingerprint from the logs")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return errUsage
+	}
+
+	config, err := config2.LoadGatewayMrc(configPath)
+	if err != nil {
+		return err
+	}
+	connPool, err := database.NewPool(ctx, config.PostgresSource)
+	if err != nil {
+		return fmt.Errorf("cannot connect to db: %w", err)
+	}
+	defer connPool.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: config.RedisAddress})
+	defer redisClient.Close()
+	sessionStore, err := sessions.NewSessionStore(config.SessionStore, redisClient, db.NewStore(connPool))
+	if err != nil {
+		return err
+	}
+	tokenMaker, err := jwt_token.NewJWTMaker(config.TokenSymmetricKey, config.AccessTokenExpiresIn, config.RefreshTokenExpiresIn)
+	if err != nil {
+		return fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	active, err := sessionStore.Active(ctx, time.Now(), *limit)
+	if err != nil {
+		return fmt.Errorf("cannot list sessions: %w", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tUSER\tCLIENT IP\tUSER AGENT\tLAST ACTIVE\tEXPIRES AT\tBLOCKED")
+	found := 0
+	for _, session := range active {
+		sessionFingerprint := logger.Fingerprint(uuid.UUID(session.ID.Bytes).String())
+		if *fingerprint != "" && sessionFingerprint != *fingerprint {
+			continue
+		}
+		found++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%t\n",
+			sessionFingerprint, sessionUser(tokenMaker, session), session.ClientIp,
+			sessions.UserAgentFamily(session.UserAgent), formatTime(session.LastActive.Time),
+			formatTime(session.ExpiresAt), session.IsBlocked.Bool)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if *fingerprint != "" {
+		fmt.Printf("%d of %d active sessions match (%s store)\n", found, len(active), config.SessionStore)
+		return nil
+	}
+	fmt.Printf("%d active sessions (%s store)\n", len(active), config.SessionStore)
+	return nil
+}
+
+// sessionUser reads the user from the refresh token, it outlives the access token.
+// Anonymous sessions have no tokens.
+func sessionUser(tokenMaker jwt_token.Maker, session db.Session) string {
+	for _, token := range []string{session.RefreshToken.String, session.AccessToken.String} {
+		if token == "" {
+			continue
+		}
+		if payload, err := tokenMaker.VerifyToken(token); err == nil {
+			return payload.Email
+		}
+	}
+	return "-"
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"job_search_platform/internal/users_mrc/db/seed"
+	db "job_search_platform/internal/users_mrc/db/sqlc"
+	"job_search_platform/internal/users_mrc/usecases"
+	config2 "job_search_platform/pkg/config"
+	"job_search_platform/pkg/database"
+	"job_search_platform/pkg/helpers/crypto"
+	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/password_policy"
+	"os"
+	"strings"
+)
+
+// openUsersStore connects to the database of users_mrc, the caller closes the pool
+func openUsersStore(ctx context.Context, configPath string) (config2.UsersMrc, *db.SQLStore, func(), error) {
+	config, err := config2.LoadUsersMrc(configPath)
+	if err != nil {
+		return config, nil, nil, err
+	}
+	connPool, err := database.NewPool(ctx, config.PostgresSource)
+	if err != nil {
+		return config, nil, nil, fmt.Errorf("cannot connect to db: %w", err)
+	}
+	if err = connPool.Ping(ctx); err != nil {
+		connPool.Close()
+		return config, nil, nil, fmt.Errorf("cannot connect to db: %w", err)
+	}
+	return config, db.NewStore(connPool), connPool.Close, nil
+}
+
+// newAuthUsecase builds the usecase the same way the users_mrc server does
+func newAuthUsecase(config config2.UsersMrc, store db.Store) (usecases.AuthUsecase, error) {
+	var usecase usecases.AuthUsecase
+	tokenMaker, err := jwt_token.NewJWTMaker(config.TokenSymmetricKey, config.AccessTokenExpiresIn, config.RefreshTokenExpiresIn)
+	if err != nil {
+		return usecase, fmt.Errorf("cannot create token maker: %w", err)
+	}
+	passwordPolicy, err := password_policy.NewPolicy(config.PasswordPolicy)
+	if err != nil {
+		return usecase, fmt.Errorf("cannot create password policy: %w", err)
+	}
+	passwordHasher, err := crypto.NewPasswordHasher(config.PasswordHashing)
+	if err != nil {
+		return usecase, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	return usecases.NewAuthUsecase(store, tokenMaker, passwordPolicy, passwordHasher, config), nil
+}
+
+func runSeed(ctx context.Context, configPath string, args []string) error {
+	if err := parseFlags(flag.NewFlagSet("seed", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	_, store, closePool, err := openUsersStore(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+	if err = seed.Run(ctx, store); err != nil {
+		return err
+	}
+	fmt.Printf("seeded %d groups and %d permissions\n", len(seed.Groups), len(seed.Permissions))
+	return nil
+}
+
+func runCreateAdmin(ctx context.Context, configPath string, args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the administrator")
+	password := flags.String("password", "", "password, read from stdin when empty")
+	firstName := flags.String("first-name", "", "first name")
+	lastName := flags.String("last-name", "", "last name")
+	langCode := flags.String("lang-code", "", "language of the emails: ru, en or kk")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errUsage
+	}
+	if err := readPassword(password); err != nil {
+		return err
+	}
+
+	config, store, closePool, err := openUsersStore(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+	usecase, err := newAuthUsecase(config, store)
+	if err != nil {
+		return err
+	}
+	// группа administrators создается сидом, на пустой базе он нужен до пользователя
+	if err = seed.Run(ctx, store); err != nil {
+		return err
+	}
+	staffArgs := &db.CreateStaffUserTxParams{
+		BaseUserInfo: db.BaseUserInfo{
+			Email:     *email,
+			Password1: *password,
+			Password2: *password,
+			FirstName: *firstName,
+			LastName:  *lastName,
+			Source:    "admin_auth",
+			LangCode:  *langCode,
+		},
+	}
+	user, _, err := usecase.CreateStaffUser(ctx, staffArgs, seed.AdministratorsGroup)
+	if err != nil {
+		return fmt.Errorf("cannot create the administrator: %w", err)
+	}
+	fmt.Printf("created administrator %s (%s)\n", user.Email, uuid.UUID(user.ID.Bytes))
+	return nil
+}
+
+func runResetPassword(ctx context.Context, configPath string, args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	password := flags.String("password", "", "new password, read from stdin when empty")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errUsage
+	}
+	if err := readPassword(password); err != nil {
+		return err
+	}
+
+	config, store, closePool, err := openUsersStore(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+	usecase, err := newAuthUsecase(config, store)
+	if err != nil {
+		return err
+	}
+	user, err := store.GetUserByEmail(ctx, *email)
+	if err != nil {
+		return userError(*email, err)
+	}
+	if _, err = usecase.ResetPassword(ctx, user.ID.Bytes, *password); err != nil {
+		return fmt.Errorf("cannot reset the password: %w", err)
+	}
+	fmt.Printf("password of %s has been reset, the refresh tokens are revoked and the issued access tokens stay valid for up to %s\n",
+		user.Email, config.AccessTokenExpiresIn)
+	return nil
+}
+
+func runBanUser(ctx context.Context, configPath string, args []string) error {
+	flags := flag.NewFlagSet("ban-user", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	unban := flags.Bool("unban", false, "lift the ban")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errUsage
+	}
+
+	config, store, closePool, err := openUsersStore(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+	usecase := usecases.NewUsersUsecase(store)
+	user, _, err := usecase.BanUser(ctx, *email, !*unban)
+	if err != nil {
+		return userError(*email, err)
+	}
+	if *unban {
+		fmt.Printf("%s is unbanned\n", user.Email)
+		return nil
+	}
+	fmt.Printf("%s is banned, the issued access tokens stay valid for up to %s\n", user.Email, config.AccessTokenExpiresIn)
+	return nil
+}
+
+// readPassword reads the first line of stdin when the flag is empty, so the password does not stay in the shell history
+func readPassword(password *string) error {
+	if *password != "" {
+		return nil
+	}
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	*password = strings.TrimRight(line, "\r\n")
+	if *password == "" {
+		return errors.Join(errors.New("password is empty"), err)
+	}
+	return nil
+}
+
+func userError(email string, err error) error {
+	if errors.Is(err, database.ErrRecordNotFound) {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return err
+}
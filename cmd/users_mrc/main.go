@@ -2,41 +2,56 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/hibiken/asynq"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/rs/zerolog/log"
-	"golang.org/x/sync/errgroup"
+	"job_search_platform/internal/users_mrc/db/seed"
 	db "job_search_platform/internal/users_mrc/db/sqlc"
 	"job_search_platform/internal/users_mrc/server"
+	"job_search_platform/internal/users_mrc/usecases"
+	"job_search_platform/pkg/app"
 	config2 "job_search_platform/pkg/config"
 	"job_search_platform/pkg/database"
+	"job_search_platform/pkg/health"
 	logger2 "job_search_platform/pkg/logger"
+	"job_search_platform/pkg/mail_sender"
+	"job_search_platform/pkg/metrics"
+	"job_search_platform/pkg/notifications"
+	"job_search_platform/pkg/openapi"
 	"job_search_platform/pkg/scheduler"
+	"job_search_platform/pkg/tracing"
 	"os"
-	"os/signal"
-	"syscall"
 )
 
-var interruptSignals = []os.Signal{
-	os.Interrupt,
-	syscall.SIGTERM,
-	syscall.SIGINT,
-}
-
 func main() {
-	config, err := config2.LoadConfig("../..", "users_mrc")
+	// `users_mrc openapi [-o file] [--check api/openapi/users_mrc.json]` needs no configuration
+	if code, ok := openapi.RunCommand(os.Args[1:], server.OpenAPI); ok {
+		os.Exit(code)
+	}
+	config, err := config2.LoadUsersMrc("../..")
+	// `users_mrc config print [--redacted]` shows what the service would start with
+	if code, ok := config2.RunCommand(os.Args[1:], &config, err); ok {
+		os.Exit(code)
+	}
 	if err != nil {
-		log.Fatal().Err(err).Msg("cannot load config")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	logger, file := logger2.ConfigureLogger(config.Environment)
+	logger, file := logger2.ConfigureLogger(config.Common)
 	defer file.Close()
-	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
-	defer stop()
-	connPool, err := pgxpool.New(ctx, config.PostgresSource)
-	defer connPool.Close()
+	application := app.New("users_mrc", config.ShutdownTimeout, logger)
+	ctx := context.Background()
+
+	shutdownTracing, err := tracing.Init(ctx, config.Common, "users_mrc")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("cannot init tracing")
+	}
+	application.OnStop("tracing", shutdownTracing)
+
+	connPool, err := database.NewPool(ctx, config.PostgresSource)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("cannot connect to db")
 	}
+	application.OnStop("postgres pool", app.Wait(connPool.Close))
 	err = connPool.Ping(ctx)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("cannot connect to db")
@@ -46,16 +61,47 @@ func main() {
 		logger.Fatal().Err(err).Msg("cannot run migration")
 	}
 	store := db.NewStore(connPool)
+	// sign-up needs the ordinary_users group, the seed is idempotent
+	if err = seed.Run(ctx, store); err != nil {
+		logger.Fatal().Err(err).Msg("cannot seed db")
+	}
 	redisOpt := asynq.RedisClientOpt{Addr: config.RedisAddress}
 	taskDistributor := scheduler.NewRedisTaskDistributor(redisOpt)
-	waitGroup, ctx := errgroup.WithContext(ctx)
-	err = scheduler.RunTaskProcessor(ctx, waitGroup, config, redisOpt, logger)
+	unsubscribeSigner, err := notifications.NewUnsubscribeSigner(config.UnsubscribeSecretKey)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("cannot run task processor")
+		logger.Fatal().Err(err).Msg("cannot create unsubscribe signer")
 	}
-	err = server.RunGinServer(config, store, taskDistributor, logger)
-	err = waitGroup.Wait()
+	notificationsUsecase := usecases.NewNotificationsUsecase(store, unsubscribeSigner)
+
+	metrics.RegisterPgxPool(connPool)
+	metrics.RegisterQueues(redisOpt, scheduler.QueueCritical, scheduler.QueueDefault)
+	if metricsServer := metrics.NewServer(config.MetricsAddress); metricsServer != nil {
+		application.Add(app.HttpServer("metrics server", metricsServer))
+	}
+
+	taskProcessor := scheduler.NewTaskProcessor(config, redisOpt, logger, &notificationsUsecase, unsubscribeSigner)
+	application.Add(app.Hook{
+		Name:    "task processor",
+		OnStart: func(context.Context) error { return taskProcessor.Start() },
+		OnStop:  app.Wait(taskProcessor.Shutdown),
+	})
+
+	readiness := health.New(config.HealthCheckTimeout)
+	readiness.Add("postgres", health.PgxPool(connPool))
+	readiness.Add("redis", health.Asynq(redisOpt))
+	if config.HealthCheckSmtp {
+		readiness.AddOptional("smtp", health.Tcp(mail_sender.ServerAddress()))
+	}
+	httpServer, err := server.NewServer(config, store, taskDistributor, unsubscribeSigner, readiness, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("cannot create gin server")
+	}
+	application.Add(httpServer.Hook())
+
+	err = application.Run(ctx)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("error from wait group")
+		logger.Error().Err(err).Msg("application stopped with an error")
+		file.Close()
+		os.Exit(1)
 	}
 }
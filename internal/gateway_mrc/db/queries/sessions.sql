@@ -30,10 +30,69 @@ SET
     access_token = COALESCE(sqlc.narg('access_token'), access_token),
     refresh_token = COALESCE(sqlc.narg('refresh_token'), refresh_token),
     session_data = COALESCE(sqlc.narg('session_data'), session_data),
-    last_active = COALESCE(sqlc.narg('last_active'), last_active)
+    last_active = COALESCE(sqlc.narg('last_active'), last_active),
+    expires_at = COALESCE(sqlc.narg('expires_at'), expires_at)
 WHERE id = sqlc.arg('id')
     RETURNING *;
 
 -- name: GetSession :one
 SELECT * FROM sessions
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: EndExpiredSessions :execrows
+UPDATE sessions
+SET
+    ended_at = sqlc.arg('ended_at'),
+    session_length_seconds = GREATEST(EXTRACT(EPOCH FROM COALESCE(last_active, created_at) - created_at), 0)::INTEGER,
+    access_token = NULL,
+    refresh_token = NULL
+WHERE id IN (
+    SELECT id FROM sessions
+    WHERE ended_at IS NULL AND expires_at < sqlc.arg('ended_at')
+    ORDER BY expires_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+);
+
+-- name: EndSession :exec
+UPDATE sessions
+SET
+    ended_at = sqlc.arg('ended_at'),
+    session_length_seconds = GREATEST(EXTRACT(EPOCH FROM COALESCE(last_active, created_at) - created_at), 0)::INTEGER,
+    access_token = NULL,
+    refresh_token = NULL
+WHERE id = sqlc.arg('id') AND ended_at IS NULL;
+
+-- name: CreateEndedSession :exec
+INSERT INTO sessions(
+    id,
+    user_agent,
+    client_ip,
+    last_active,
+    expires_at,
+    session_length_seconds,
+    ended_at,
+    created_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: DeleteEndedSessions :execrows
+DELETE FROM sessions
+WHERE ended_at < $1;
+
+-- name: CountActiveSessionsByUserAgent :many
+SELECT user_agent, COUNT(*) AS sessions_count FROM sessions
+WHERE ended_at IS NULL AND expires_at >= $1
+GROUP BY user_agent;
+
+-- name: GetEndedSessionsStats :one
+SELECT
+    COUNT(*) AS ended_sessions,
+    COALESCE(AVG(session_length_seconds), 0)::FLOAT8 AS average_length_seconds
+FROM sessions
+WHERE ended_at IS NOT NULL;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE ended_at IS NULL AND expires_at >= $1
+ORDER BY last_active DESC NULLS LAST
+LIMIT $2;
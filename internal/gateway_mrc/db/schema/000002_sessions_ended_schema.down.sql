@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS sessions_ended_at_idx;
+DROP INDEX IF EXISTS sessions_expires_at_idx;
+
+ALTER TABLE sessions ALTER COLUMN session_length_seconds SET DEFAULT 0;
+ALTER TABLE sessions DROP COLUMN IF EXISTS ended_at;
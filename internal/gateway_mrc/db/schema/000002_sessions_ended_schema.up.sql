@@ -0,0 +1,6 @@
+ALTER TABLE sessions ADD COLUMN ended_at TIMESTAMPTZ;
+ALTER TABLE sessions ALTER COLUMN session_length_seconds DROP DEFAULT;
+UPDATE sessions SET session_length_seconds = NULL WHERE ended_at IS NULL;
+
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at) WHERE ended_at IS NULL;
+CREATE INDEX sessions_ended_at_idx ON sessions (ended_at) WHERE ended_at IS NOT NULL;
@@ -22,4 +22,5 @@ type Session struct {
 	ExpiresAt            time.Time          `json:"expires_at"`
 	SessionLengthSeconds pgtype.Int4        `json:"session_length_seconds"`
 	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	EndedAt              pgtype.Timestamptz `json:"ended_at"`
 }
@@ -6,15 +6,23 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
 	BlockSession(ctx context.Context, arg BlockSessionParams) (Session, error)
+	CountActiveSessionsByUserAgent(ctx context.Context, expiresAt time.Time) ([]CountActiveSessionsByUserAgentRow, error)
+	CreateEndedSession(ctx context.Context, arg CreateEndedSessionParams) error
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	DeleteEndedSessions(ctx context.Context, endedAt pgtype.Timestamptz) (int64, error)
 	DeleteSession(ctx context.Context, id pgtype.UUID) error
+	EndExpiredSessions(ctx context.Context, arg EndExpiredSessionsParams) (int64, error)
+	EndSession(ctx context.Context, arg EndSessionParams) error
+	GetEndedSessionsStats(ctx context.Context) (GetEndedSessionsStatsRow, error)
 	GetSession(ctx context.Context, id pgtype.UUID) (Session, error)
+	ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error)
 	UpdateSessionData(ctx context.Context, arg UpdateSessionDataParams) (Session, error)
 }
 
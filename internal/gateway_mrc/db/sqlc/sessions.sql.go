@@ -17,7 +17,7 @@ UPDATE sessions
 SET
     is_blocked = COALESCE($1, is_blocked)
 WHERE id = $2
-    RETURNING id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at
+    RETURNING id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at, ended_at
 `
 
 type BlockSessionParams struct {
@@ -40,10 +40,80 @@ func (q *Queries) BlockSession(ctx context.Context, arg BlockSessionParams) (Ses
 		&i.ExpiresAt,
 		&i.SessionLengthSeconds,
 		&i.CreatedAt,
+		&i.EndedAt,
 	)
 	return i, err
 }
 
+const countActiveSessionsByUserAgent = `-- name: CountActiveSessionsByUserAgent :many
+SELECT user_agent, COUNT(*) AS sessions_count FROM sessions
+WHERE ended_at IS NULL AND expires_at >= $1
+GROUP BY user_agent
+`
+
+type CountActiveSessionsByUserAgentRow struct {
+	UserAgent     string `json:"user_agent"`
+	SessionsCount int64  `json:"sessions_count"`
+}
+
+func (q *Queries) CountActiveSessionsByUserAgent(ctx context.Context, expiresAt time.Time) ([]CountActiveSessionsByUserAgentRow, error) {
+	rows, err := q.db.Query(ctx, countActiveSessionsByUserAgent, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountActiveSessionsByUserAgentRow{}
+	for rows.Next() {
+		var i CountActiveSessionsByUserAgentRow
+		if err := rows.Scan(&i.UserAgent, &i.SessionsCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createEndedSession = `-- name: CreateEndedSession :exec
+INSERT INTO sessions(
+    id,
+    user_agent,
+    client_ip,
+    last_active,
+    expires_at,
+    session_length_seconds,
+    ended_at,
+    created_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type CreateEndedSessionParams struct {
+	ID                   pgtype.UUID        `json:"id"`
+	UserAgent            string             `json:"user_agent"`
+	ClientIp             string             `json:"client_ip"`
+	LastActive           pgtype.Timestamptz `json:"last_active"`
+	ExpiresAt            time.Time          `json:"expires_at"`
+	SessionLengthSeconds pgtype.Int4        `json:"session_length_seconds"`
+	EndedAt              pgtype.Timestamptz `json:"ended_at"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) CreateEndedSession(ctx context.Context, arg CreateEndedSessionParams) error {
+	_, err := q.db.Exec(ctx, createEndedSession,
+		arg.ID,
+		arg.UserAgent,
+		arg.ClientIp,
+		arg.LastActive,
+		arg.ExpiresAt,
+		arg.SessionLengthSeconds,
+		arg.EndedAt,
+		arg.CreatedAt,
+	)
+	return err
+}
+
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions(
     session_data,
@@ -56,7 +126,7 @@ INSERT INTO sessions(
     last_active
 
 )VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
-    RETURNING id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at
+    RETURNING id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at, ended_at
 `
 
 type CreateSessionParams struct {
@@ -92,10 +162,24 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.ExpiresAt,
 		&i.SessionLengthSeconds,
 		&i.CreatedAt,
+		&i.EndedAt,
 	)
 	return i, err
 }
 
+const deleteEndedSessions = `-- name: DeleteEndedSessions :execrows
+DELETE FROM sessions
+WHERE ended_at < $1
+`
+
+func (q *Queries) DeleteEndedSessions(ctx context.Context, endedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteEndedSessions, endedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteSession = `-- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = $1
@@ -106,8 +190,77 @@ func (q *Queries) DeleteSession(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const endExpiredSessions = `-- name: EndExpiredSessions :execrows
+UPDATE sessions
+SET
+    ended_at = $1,
+    session_length_seconds = GREATEST(EXTRACT(EPOCH FROM COALESCE(last_active, created_at) - created_at), 0)::INTEGER,
+    access_token = NULL,
+    refresh_token = NULL
+WHERE id IN (
+    SELECT id FROM sessions
+    WHERE ended_at IS NULL AND expires_at < $1
+    ORDER BY expires_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+type EndExpiredSessionsParams struct {
+	EndedAt pgtype.Timestamptz `json:"ended_at"`
+	Limit   int32              `json:"limit"`
+}
+
+func (q *Queries) EndExpiredSessions(ctx context.Context, arg EndExpiredSessionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, endExpiredSessions, arg.EndedAt, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const endSession = `-- name: EndSession :exec
+UPDATE sessions
+SET
+    ended_at = $1,
+    session_length_seconds = GREATEST(EXTRACT(EPOCH FROM COALESCE(last_active, created_at) - created_at), 0)::INTEGER,
+    access_token = NULL,
+    refresh_token = NULL
+WHERE id = $2 AND ended_at IS NULL
+`
+
+type EndSessionParams struct {
+	EndedAt pgtype.Timestamptz `json:"ended_at"`
+	ID      pgtype.UUID        `json:"id"`
+}
+
+func (q *Queries) EndSession(ctx context.Context, arg EndSessionParams) error {
+	_, err := q.db.Exec(ctx, endSession, arg.EndedAt, arg.ID)
+	return err
+}
+
+const getEndedSessionsStats = `-- name: GetEndedSessionsStats :one
+SELECT
+    COUNT(*) AS ended_sessions,
+    COALESCE(AVG(session_length_seconds), 0)::FLOAT8 AS average_length_seconds
+FROM sessions
+WHERE ended_at IS NOT NULL
+`
+
+type GetEndedSessionsStatsRow struct {
+	EndedSessions        int64   `json:"ended_sessions"`
+	AverageLengthSeconds float64 `json:"average_length_seconds"`
+}
+
+func (q *Queries) GetEndedSessionsStats(ctx context.Context) (GetEndedSessionsStatsRow, error) {
+	row := q.db.QueryRow(ctx, getEndedSessionsStats)
+	var i GetEndedSessionsStatsRow
+	err := row.Scan(&i.EndedSessions, &i.AverageLengthSeconds)
+	return i, err
+}
+
 const getSession = `-- name: GetSession :one
-SELECT id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at FROM sessions
+SELECT id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at, ended_at FROM sessions
 WHERE id = $1
 `
 
@@ -126,19 +279,66 @@ func (q *Queries) GetSession(ctx context.Context, id pgtype.UUID) (Session, erro
 		&i.ExpiresAt,
 		&i.SessionLengthSeconds,
 		&i.CreatedAt,
+		&i.EndedAt,
 	)
 	return i, err
 }
 
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at, ended_at FROM sessions
+WHERE ended_at IS NULL AND expires_at >= $1
+ORDER BY last_active DESC NULLS LAST
+LIMIT $2
+`
+
+type ListActiveSessionsParams struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Limit     int32     `json:"limit"`
+}
+
+func (q *Queries) ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error) {
+	rows, err := q.db.Query(ctx, listActiveSessions, arg.ExpiresAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccessToken,
+			&i.RefreshToken,
+			&i.SessionData,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.IsBlocked,
+			&i.LastActive,
+			&i.ExpiresAt,
+			&i.SessionLengthSeconds,
+			&i.CreatedAt,
+			&i.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateSessionData = `-- name: UpdateSessionData :one
 UPDATE sessions
 SET
     access_token = COALESCE($1, access_token),
     refresh_token = COALESCE($2, refresh_token),
     session_data = COALESCE($3, session_data),
-    last_active = COALESCE($4, last_active)
-WHERE id = $5
-    RETURNING id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at
+    last_active = COALESCE($4, last_active),
+    expires_at = COALESCE($5, expires_at)
+WHERE id = $6
+    RETURNING id, access_token, refresh_token, session_data, user_agent, client_ip, is_blocked, last_active, expires_at, session_length_seconds, created_at, ended_at
 `
 
 type UpdateSessionDataParams struct {
@@ -146,6 +346,7 @@ type UpdateSessionDataParams struct {
 	RefreshToken pgtype.Text        `json:"refresh_token"`
 	SessionData  pgtype.Text        `json:"session_data"`
 	LastActive   pgtype.Timestamptz `json:"last_active"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
 	ID           pgtype.UUID        `json:"id"`
 }
 
@@ -155,6 +356,7 @@ func (q *Queries) UpdateSessionData(ctx context.Context, arg UpdateSessionDataPa
 		arg.RefreshToken,
 		arg.SessionData,
 		arg.LastActive,
+		arg.ExpiresAt,
 		arg.ID,
 	)
 	var i Session
@@ -170,6 +372,7 @@ func (q *Queries) UpdateSessionData(ctx context.Context, arg UpdateSessionDataPa
 		&i.ExpiresAt,
 		&i.SessionLengthSeconds,
 		&i.CreatedAt,
+		&i.EndedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"job_search_platform/internal/gateway_mrc/proxy"
+	"job_search_platform/internal/gateway_mrc/routing"
+	"job_search_platform/internal/gateway_mrc/usecases"
+	"job_search_platform/pkg/helpers/server"
+	"net/http"
+	"sort"
+)
+
+type AdminHandler struct {
+	routes          *routing.RouteTable
+	forwarder       *proxy.Forwarder
+	sessionsUsecase usecases.SessionsUsecase
+}
+
+func NewAdminHandler(routes *routing.RouteTable, forwarder *proxy.Forwarder, sessionsUsecase usecases.SessionsUsecase) AdminHandler {
+	return AdminHandler{routes: routes, forwarder: forwarder, sessionsUsecase: sessionsUsecase}
+}
+
+type upstreamService struct {
+	Service   string   `json:"service"`
+	Instances []string `json:"instances"`
+}
+
+// GetUpstreams shows the registered instances and the circuit breaker state of every instance that got traffic
+func (handler *AdminHandler) GetUpstreams(ctx *gin.Context) {
+	table := handler.routes.Current()
+	names := make([]string, 0, len(table.Services))
+	for name := range table.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	services := make([]upstreamService, 0, len(names))
+	for _, name := range names {
+		services = append(services, upstreamService{
+			Service:   name,
+			Instances: handler.routes.Registry().Instances(name),
+		})
+	}
+	response := gin.H{"services": services, "breakers": handler.forwarder.Breakers().Statuses()}
+	ctx.JSON(http.StatusOK, server.Response(ctx, nil, server.SUCCESS_CODE, response))
+}
+
+// GetSessionStats reports the active sessions by user agent family and the average length of the ended ones
+func (handler *AdminHandler) GetSessionStats(ctx *gin.Context) {
+	stats, errCode, err := handler.sessionsUsecase.GetStats(ctx)
+	if err != nil {
+		server.AbortErr(ctx, errCode, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, server.Response(ctx, nil, server.SUCCESS_CODE, stats))
+}
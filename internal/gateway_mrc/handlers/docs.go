@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"io"
+	"job_search_platform/internal/gateway_mrc/middleware"
+	"job_search_platform/internal/gateway_mrc/routing"
+	"job_search_platform/pkg/openapi"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	docsFetchTimeout = 5 * time.Second
+	maxSpecSize      = 4 << 20
+	// partialDocsTTL is the cache time of a document without some of the services,
+	// they are asked again soon instead of being missing for the whole TTL
+	partialDocsTTL = 5 * time.Second
+
+	sessionScheme = "session"
+	csrfScheme    = "csrf"
+)
+
+//go:embed docs/index.html
+var swaggerUi []byte
+
+// DocsHandler serves the specification of the public API: the documents of the upstreams
+// restricted to the operations the route table exposes, with the security of the gateway
+type DocsHandler struct {
+	routes     *routing.RouteTable
+	cookieName string
+	client     *http.Client
+	cacheTTL   time.Duration
+	cache      *docsCache
+}
+
+// docsCache keeps the aggregated specification, it is built again when the TTL passes
+// or the route table is reloaded. A document without some of the services is kept for partialDocsTTL.
+type docsCache struct {
+	mu      sync.Mutex
+	table   *routing.Table
+	body    []byte
+	expires time.Time
+}
+
+func NewDocsHandler(routes *routing.RouteTable, cookieName string, cacheTTL time.Duration) DocsHandler {
+	return DocsHandler{
+		routes:     routes,
+		cookieName: cookieName,
+		client:     &http.Client{Timeout: docsFetchTimeout},
+		cacheTTL:   cacheTTL,
+		cache:      &docsCache{},
+	}
+}
+
+// UI is the Swagger UI page, it loads openapi.json next to it
+func (handler *DocsHandler) UI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUi)
+}
+
+// Spec serves the cached specification. The requests wait for a single rebuild,
+// so the upstreams are asked at most once per TTL whatever the traffic on /docs is.
+func (handler *DocsHandler) Spec(ctx *gin.Context) {
+	table := handler.routes.Current()
+	cache := handler.cache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.body == nil || cache.table != table || time.Now().After(cache.expires) {
+		doc, complete := handler.aggregate(ctx, table)
+		body, err := json.Marshal(doc)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		ttl := handler.cacheTTL
+		if !complete {
+			ttl = min(ttl, partialDocsTTL)
+		}
+		cache.body, cache.table, cache.expires = body, table, time.Now().Add(ttl)
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", cache.body)
+}
+
+// aggregate merges the documents of the services, a service that does not answer is left out
+// and complete is false
+func (handler *DocsHandler) aggregate(ctx *gin.Context, table *routing.Table) (doc *openapi.Document, complete bool) {
+	doc = openapi.New("job_search_platform", "v1")
+	doc.Components.SecuritySchemes[sessionScheme] = &openapi.SecurityScheme{
+		Type: "apiKey", In: "cookie", Name: handler.cookieName,
+		Description: "gateway session, started by sign-in",
+	}
+	doc.Components.SecuritySchemes[csrfScheme] = &openapi.SecurityScheme{
+		Type: "apiKey", In: "header", Name: middleware.CsrfTokenHeader,
+		Description: "token of the session, required on POST, PUT, PATCH and DELETE",
+	}
+
+	names := make([]string, 0, len(table.Services))
+	for name := range table.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var unavailable []string
+	for _, name := range names {
+		source, err := handler.fetch(ctx, name)
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("service", name).Msg("cannot get the service specification")
+			unavailable = append(unavailable, name)
+			continue
+		}
+		doc.Merge(source, name, func(path, method string, operation *openapi.Operation) bool {
+			route, ok := table.Match(strings.ToUpper(method), path)
+			if !ok || route.Service != name || route.Handler == routing.HandlerCsrfToken {
+				return false
+			}
+			operation.Security = security(route, strings.ToUpper(method))
+			if route.Handler == routing.HandlerSignIn {
+				hideTokens(operation)
+			}
+			return true
+		})
+	}
+	addGatewayOperations(doc, table)
+	if len(unavailable) > 0 {
+		doc.Info.Description = "Not available now: " + strings.Join(unavailable, ", ")
+	}
+	return doc, len(unavailable) == 0
+}
+
+func (handler *DocsHandler) fetch(ctx *gin.Context, service string) (*openapi.Document, error) {
+	instance, err := handler.routes.Registry().Resolve(service)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, instance+openapi.SpecPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := handler.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %d", openapi.SpecPath, resp.StatusCode)
+	}
+	var doc openapi.Document
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxSpecSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("cannot decode the specification: %w", err)
+	}
+	return &doc, nil
+}
+
+// security is the requirement of the route: the session for the private routes,
+// the CSRF token for the unsafe methods unless the route skips the check
+func security(route *routing.Route, method string) []map[string][]string {
+	requirement := make(map[string][]string)
+	if route.Auth != routing.AuthPublic {
+		requirement[sessionScheme] = []string{}
+	}
+	if !route.SkipCsrf && !middleware.IsSafeMethod(method) {
+		requirement[csrfScheme] = []string{}
+	}
+	if len(requirement) == 0 {
+		return nil
+	}
+	return []map[string][]string{requirement}
+}
+
+// hideTokens describes the sign-in response of the gateway: the tokens stay in the session (ProxySignInReq)
+func hideTokens(operation *openapi.Operation) {
+	response, ok := operation.Responses["200"]
+	if !ok {
+		return
+	}
+	response.Description = "Session started, the CSRF token is in " + middleware.CsrfTokenHeader
+	for _, media := range response.Content {
+		if media.Schema != nil && media.Schema.Properties != nil {
+			media.Schema.Properties["body"] = &openapi.Schema{Nullable: true}
+		}
+	}
+	operation.Responses["200"] = response
+}
+
+// addGatewayOperations describes the routes served by the gateway itself
+func addGatewayOperations(doc *openapi.Document, table *routing.Table) {
+	for _, route := range table.Routes {
+		if route.Handler != routing.HandlerCsrfToken {
+			continue
+		}
+		body := &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"csrf_token": {Type: "string"}},
+		}
+		doc.Paths[route.Prefix] = openapi.PathItem{"get": {
+			OperationId: "CsrfToken",
+			Summary:     "Start a session and get its CSRF token",
+			Tags:        []string{"gateway"},
+			Responses: map[string]openapi.Response{"200": {
+				Description: "Success",
+				Content: map[string]openapi.MediaType{openapi.JsonContentType: {Schema: &openapi.Schema{
+					Type:       "object",
+					Properties: map[string]*openapi.Schema{"code": {Type: "integer", Format: "int32"}, "body": body},
+				}}},
+			}},
+		}}
+	}
+}
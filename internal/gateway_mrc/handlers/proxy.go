@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
 	"io"
+	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"job_search_platform/internal/gateway_mrc/middleware"
+	"job_search_platform/internal/gateway_mrc/proxy"
+	"job_search_platform/internal/gateway_mrc/sessions"
 	"job_search_platform/internal/gateway_mrc/usecases"
 	"job_search_platform/pkg/config"
 	"job_search_platform/pkg/entities/common"
@@ -13,103 +19,197 @@ import (
 	"net/http"
 )
 
+// maxSignInResponseSize limits the buffered sign-in response, it only carries the token pair
+const maxSignInResponseSize = 64 << 10
+
 type ProxyHandler struct {
 	sessionsUsecase usecases.SessionsUsecase
-	config          config.Config
+	config          config.GatewayMrc
 	jwtMaker        jwt_token.Maker
+	forwarder       *proxy.Forwarder
+	cookie          sessions.Cookie
 }
 
-func NewProxyHandler(jwtMaker jwt_token.Maker, sessionsUsecase usecases.SessionsUsecase, config config.Config) ProxyHandler {
-	return ProxyHandler{sessionsUsecase: sessionsUsecase, jwtMaker: jwtMaker, config: config}
+func NewProxyHandler(
+	jwtMaker jwt_token.Maker,
+	sessionsUsecase usecases.SessionsUsecase,
+	config config.GatewayMrc,
+	forwarder *proxy.Forwarder,
+	cookie sessions.Cookie,
+) ProxyHandler {
+	return ProxyHandler{
+		sessionsUsecase: sessionsUsecase,
+		jwtMaker:        jwtMaker,
+		config:          config,
+		forwarder:       forwarder,
+		cookie:          cookie,
+	}
 }
 
-func (c *ProxyHandler) ProxySignInReq(ctx *gin.Context, target string) {
+func (c *ProxyHandler) ProxySignInReq(ctx *gin.Context, service string) {
 	var payload common.SignInResponse
-	sessionIdStr, exists := ctx.Get("sessionId")
-	if !exists {
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, server.Response(nil, server.GET_COOKIE_ERR_CODE, nil))
+	previous, exists := sessions.FromContext(ctx)
+
+	resp, err := c.forwarder.Forward(ctx, service, func(req *http.Request) {
+		// magic-link вход проверяет, что ссылка открыта в той же сессии
+		if exists {
+			req.Header.Set(server.SessionIdHeader, ctx.GetString("sessionId"))
+		}
+	})
+	if err != nil {
+		upstreamErr(ctx, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.copyResponse(ctx, resp)
 		return
 	}
 
-	url := server.GetReqFullUrl(ctx, target)
-	fmt.Print(ctx.Request.Body)
-	resp, err := server.CreateAndSendRequest(
-		ctx.Request.Method,
-		url,
-		ctx.Request.Body,
-		ctx.Request.Header,
+	// токены остаются в сессии gateway и не уходят клиенту
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSignInResponseSize))
+	if err != nil {
+		server.AbortErr(ctx, server.PARSING_RESPONSE_ERR_CODE, err)
+		return
+	}
+	err = json.Unmarshal(body, &payload)
+	if err != nil {
+		server.AbortErr(ctx, server.PARSING_RESPONSE_ERR_CODE, err)
+		return
+	}
+	session, statusCode, err := c.sessionsUsecase.RotateSession(
+		ctx, previous, ctx.ClientIP(), ctx.Request.UserAgent(), payload.Body.AccessToken, payload.Body.RefreshToken,
 	)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, server.Response(err, server.CREATING_REQUEST_ERR_CODE, nil))
+		server.AbortErr(ctx, statusCode, err)
 		return
 	}
-	defer resp.Body.Close()
+	c.startSession(ctx, session)
+	if _, ok := c.issueCsrfToken(ctx); !ok {
+		return
+	}
+	ctx.JSON(resp.StatusCode, server.Response(ctx, nil, server.SUCCESS_CODE, nil))
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (c *ProxyHandler) ProxyCommonReq(ctx *gin.Context, service string) {
+	session, exists := sessions.FromContext(ctx)
+	resp, err := c.forwarder.Forward(ctx, service, func(req *http.Request) {
+		if !exists {
+			return
+		}
+		if session.AccessToken.Valid {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", session.AccessToken.String))
+		}
+		req.Header.Set(server.SessionIdHeader, ctx.GetString("sessionId"))
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
+		upstreamErr(ctx, err)
 		return
 	}
+	defer resp.Body.Close()
+	c.copyResponse(ctx, resp)
+}
 
-	if resp.StatusCode == http.StatusOK {
-		err = json.Unmarshal(body, &payload)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusInternalServerError, server.Response(err, server.PARSING_RESPONSE_ERR_CODE, nil))
-			return
+// ProxyLogoutReq revokes the refresh token upstream, ends the session and continues with a new anonymous one
+func (c *ProxyHandler) ProxyLogoutReq(ctx *gin.Context, service string) {
+	if ctx.Request.Method == http.MethodGet {
+		// GET /logout оставлен для старых клиентов, RFC 9745
+		ctx.Header("Deprecation", "true")
+	}
+	session, exists := sessions.FromContext(ctx)
+	if exists {
+		if session.RefreshToken.Valid {
+			c.revokeRefreshToken(ctx, service, session)
 		}
-		refreshToken := payload.Body.RefreshToken
-		accessToken := payload.Body.AccessToken
-		_, statusCode, err := c.sessionsUsecase.UpdateSession(ctx, sessionIdStr.(string), refreshToken, accessToken)
+		statusCode, err := c.sessionsUsecase.EndSession(ctx, session.ID.Bytes)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(err, statusCode, nil))
+			server.AbortErr(ctx, statusCode, err)
 			return
 		}
+		sessions.ClearContext(ctx)
 	}
-	if resp.StatusCode == http.StatusOK {
-		ctx.JSON(resp.StatusCode, server.Response(nil, server.SUCCESS_CODE, nil))
-	} else {
-		ctx.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	if _, ok := c.ensureSession(ctx); !ok {
+		return
 	}
-}
-
-func (c *ProxyHandler) ProxyCommonReq(ctx *gin.Context, target string) {
-	sessionIdStr, exists := ctx.Get("sessionId")
-	if !exists {
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, server.Response(nil, server.GET_COOKIE_ERR_CODE, nil))
+	if _, ok := c.issueCsrfToken(ctx); !ok {
 		return
 	}
+	ctx.JSON(http.StatusOK, server.Response(ctx, nil, server.SUCCESS_CODE, nil))
+}
 
-	session, errCode, err := c.sessionsUsecase.GetSession(ctx, sessionIdStr.(string))
+// revokeRefreshToken sends the refresh token of the session to the upstream logout,
+// a failure does not stop the local logout, the token then lives until it expires
+func (c *ProxyHandler) revokeRefreshToken(ctx *gin.Context, service string, session db.Session) {
+	body, err := json.Marshal(map[string]string{"refresh_token": session.RefreshToken.String})
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, server.Response(err, errCode, nil))
 		return
 	}
-	url := server.GetReqFullUrl(ctx, target)
-	headers := ctx.Request.Header.Clone()
-	headers.Set("Authorization", fmt.Sprintf("Bearer %s", session.AccessToken.String))
-
-	resp, err := server.CreateAndSendRequest(
-		ctx.Request.Method,
-		url,
-		ctx.Request.Body,
-		headers,
-	)
+	resp, err := c.forwarder.Forward(ctx, service, func(req *http.Request) {
+		req.Method = http.MethodPost
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", session.AccessToken.String))
+		req.Header.Set(server.SessionIdHeader, ctx.GetString("sessionId"))
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, server.Response(err, server.CREATING_REQUEST_ERR_CODE, nil))
+		log.Ctx(ctx).Warn().Err(err).Msg("cannot revoke refresh token on logout")
 		return
 	}
 	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxSignInResponseSize))
+	if resp.StatusCode != http.StatusOK {
+		log.Ctx(ctx).Warn().Int("status", resp.StatusCode).Msg("upstream refused to revoke refresh token on logout")
+	}
+}
 
-	body, err := io.ReadAll(resp.Body)
+// ensureSession returns the session of the request and creates it for anonymous clients,
+// it returns false when the request was aborted
+func (c *ProxyHandler) ensureSession(ctx *gin.Context) (db.Session, bool) {
+	if session, exists := sessions.FromContext(ctx); exists {
+		return session, true
+	}
+	session, errCode, err := c.sessionsUsecase.CreateSession(ctx, ctx.ClientIP(), ctx.Request.UserAgent())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, server.Response(err, server.CREATING_REQUEST_ERR_CODE, nil))
-		return
+		server.AbortErr(ctx, errCode, err)
+		return session, false
 	}
+	c.startSession(ctx, session)
+	return session, true
+}
 
-	ctx.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+func (c *ProxyHandler) startSession(ctx *gin.Context, session db.Session) {
+	sessions.SetContext(ctx, session)
+	sessionId, _ := session.ID.Value()
+	c.cookie.Set(ctx, sessionId.(string))
 }
 
-func (c *ProxyHandler) ProxyLogoutReq(ctx *gin.Context) {
-	ctx.SetCookie("session_id", "", -1, "/", "localhost", false, true)
-	ctx.Status(http.StatusOK)
+// issueCsrfToken returns the CSRF token of the current session in X-CSRF-Token,
+// it returns false when the request was aborted
+func (c *ProxyHandler) issueCsrfToken(ctx *gin.Context) (string, bool) {
+	session, _ := sessions.FromContext(ctx)
+	token, errCode, err := c.sessionsUsecase.CsrfToken(ctx, &session)
+	if err != nil {
+		server.AbortErr(ctx, errCode, err)
+		return "", false
+	}
+	sessions.SetContext(ctx, session)
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header(middleware.CsrfTokenHeader, token)
+	return token, true
+}
+
+func upstreamErr(ctx *gin.Context, err error) {
+	status, errCode := proxy.ErrorStatus(err)
+	log.Ctx(ctx).Warn().Err(err).Str("path", ctx.Request.URL.Path).Int("status", status).Msg("upstream request failed")
+	server.AbortErr(ctx, errCode, err)
+}
+
+func (c *ProxyHandler) copyResponse(ctx *gin.Context, resp *http.Response) {
+	// заголовки уже отправлены, клиенту остается только оборванный ответ
+	if err := c.forwarder.CopyResponse(ctx, resp); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("url", resp.Request.URL.String()).Msg("failed to stream upstream response")
+		ctx.Abort()
+	}
 }
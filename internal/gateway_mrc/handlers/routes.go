@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"io"
+	"job_search_platform/internal/gateway_mrc/middleware"
+	"job_search_platform/internal/gateway_mrc/ratelimit"
+	"job_search_platform/internal/gateway_mrc/routing"
+	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/logger"
+	"job_search_platform/pkg/metrics"
+	"job_search_platform/pkg/validation"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RouteHandler dispatches every /api request by the route table, so the table can be reloaded
+// without rebuilding the gin router
+type RouteHandler struct {
+	routes       *routing.RouteTable
+	proxy        ProxyHandler
+	authenticate func(ctx *gin.Context) bool
+	limiter      ratelimit.Limiter
+	validator    *validation.Validator
+}
+
+// NewRouteHandler creates the dispatcher, limiter may be nil to turn rate limiting off
+func NewRouteHandler(
+	routes *routing.RouteTable,
+	proxy ProxyHandler,
+	authenticate func(ctx *gin.Context) bool,
+	limiter ratelimit.Limiter,
+) RouteHandler {
+	return RouteHandler{
+		routes:       routes,
+		proxy:        proxy,
+		authenticate: authenticate,
+		limiter:      limiter,
+		validator:    validation.New(),
+	}
+}
+
+func (handler *RouteHandler) Dispatch(ctx *gin.Context) {
+	table := handler.routes.Current()
+	route, ok := table.Match(ctx.Request.Method, ctx.Request.URL.Path)
+	if !ok {
+		server.AbortErr(ctx, server.ROUTE_NOT_FOUND_ERR_CODE, nil)
+		return
+	}
+	// префикс маршрута вместо /api/*path, чтобы число серий не зависело от путей клиентов
+	metrics.SetRoute(ctx, route.Prefix)
+	logger.Annotate(ctx, "route", route.Prefix)
+	// лимиты по IP, сессии и ключу проверяются до обращения к хранилищу сессий
+	class := table.RateLimits[route.RateLimit]
+	if !handler.rateLimit(ctx, route.RateLimit, class, routing.LimitByIp, routing.LimitBySession, routing.LimitByApiKey) {
+		return
+	}
+	if !route.SkipCsrf && !middleware.CheckCsrf(ctx) {
+		return
+	}
+	if !handler.authorize(ctx, route) {
+		return
+	}
+	if !handler.rateLimit(ctx, route.RateLimit, class, routing.LimitByUser) {
+		return
+	}
+
+	if ctx.Request.ContentLength > route.BodyLimit {
+		server.AbortErr(ctx, server.REQUEST_TOO_LARGE_ERR_CODE, nil)
+		return
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, route.BodyLimit)
+	if len(route.Validate) > 0 && routing.HasBody(ctx.Request.Method) && !handler.validateBody(ctx, route) {
+		return
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), route.Timeout)
+	defer cancel()
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+
+	if route.CreateSession {
+		if _, ok := handler.proxy.ensureSession(ctx); !ok {
+			return
+		}
+	}
+	switch route.Handler {
+	case routing.HandlerLogout:
+		handler.proxy.ProxyLogoutReq(ctx, route.Service)
+	case routing.HandlerCsrfToken:
+		handler.csrfToken(ctx)
+	case routing.HandlerSignIn:
+		handler.proxy.ProxySignInReq(ctx, route.Service)
+	default:
+		handler.proxy.ProxyCommonReq(ctx, route.Service)
+	}
+}
+
+// validateBody checks the JSON body by the route rules, the body is buffered and replaced for the proxy
+func (handler *RouteHandler) validateBody(ctx *gin.Context, route *routing.Route) bool {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			server.AbortErr(ctx, server.REQUEST_TOO_LARGE_ERR_CODE, err)
+		} else {
+			server.AbortErr(ctx, server.INVALID_DATA_ERR_CODE, err)
+		}
+		return false
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	ctx.Request.ContentLength = int64(len(body))
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(body, &data); err != nil {
+		server.AbortErr(ctx, server.INVALID_DATA_ERR_CODE, err)
+		return false
+	}
+	if err = handler.validator.Map(data, route.Validate); err != nil {
+		server.AbortErr(ctx, server.INVALID_DATA_ERR_CODE, err)
+		return false
+	}
+	return true
+}
+
+// csrfToken starts a session when there is none and returns its CSRF token
+func (handler *RouteHandler) csrfToken(ctx *gin.Context) {
+	if _, ok := handler.proxy.ensureSession(ctx); !ok {
+		return
+	}
+	token, ok := handler.proxy.issueCsrfToken(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, server.Response(ctx, nil, server.SUCCESS_CODE, gin.H{"csrf_token": token}))
+}
+
+func (handler *RouteHandler) authorize(ctx *gin.Context, route *routing.Route) bool {
+	switch route.Auth {
+	case routing.AuthSession:
+		return handler.authenticate(ctx)
+	case routing.AuthPermission:
+		if !handler.authenticate(ctx) {
+			return false
+		}
+		jwtPayload, exists := jwt_token.GetJWTPayload(ctx)
+		if !exists || !route.HasRole(jwtPayload.Groups) {
+			server.AbortErr(ctx, server.PERMISSION_DENIED_ERR_CODE, nil)
+			return false
+		}
+	}
+	return true
+}
+
+// rateLimit takes a token from every bucket of the class the request has a key for.
+// The most restrictive bucket is reported in the RateLimit-* headers, redis errors let the request through.
+func (handler *RouteHandler) rateLimit(ctx *gin.Context, className string, class routing.RateLimitClass, keys ...string) bool {
+	if handler.limiter == nil || len(class) == 0 {
+		return true
+	}
+	for _, key := range keys {
+		limit, ok := class[key]
+		if !ok {
+			continue
+		}
+		id := rateLimitId(ctx, key)
+		if id == "" {
+			continue
+		}
+		result, err := handler.limiter.Allow(ctx, className+":"+key+":"+id, limit)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("key", key).Msg("rate limiter is unavailable")
+			return true
+		}
+		setRateLimitHeaders(ctx, result)
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			server.AbortErr(ctx, server.RATE_LIMIT_EXCEEDED_ERR_CODE, nil)
+			return false
+		}
+	}
+	return true
+}
+
+func rateLimitId(ctx *gin.Context, key string) string {
+	switch key {
+	case routing.LimitByIp:
+		return ctx.ClientIP()
+	case routing.LimitBySession:
+		return ctx.GetString("sessionId")
+	case routing.LimitByApiKey:
+		apiKey := ctx.GetHeader(middleware.ApiKeyHeader)
+		if apiKey == "" {
+			return ""
+		}
+		// сам ключ в redis не храним
+		digest := sha256.Sum256([]byte(apiKey))
+		return hex.EncodeToString(digest[:16])
+	case routing.LimitByUser:
+		if jwtPayload, exists := jwt_token.GetJWTPayload(ctx); exists && jwtPayload != nil {
+			return jwtPayload.UserId.String()
+		}
+	}
+	return ""
+}
+
+func setRateLimitHeaders(ctx *gin.Context, result ratelimit.Result) {
+	header := ctx.Writer.Header()
+	if current, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err == nil && current < result.Remaining {
+		return
+	}
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"job_search_platform/internal/gateway_mrc/usecases"
+	"job_search_platform/pkg/config"
+	"job_search_platform/pkg/metrics"
+	"job_search_platform/pkg/scheduler"
+	"job_search_platform/pkg/tracing"
+)
+
+const (
+	TaskCleanupSessions = "task:cleanup_sessions"
+
+	// QueueGateway is separate from the users_mrc queues, the services may share a redis
+	QueueGateway = "gateway"
+)
+
+// SessionJobs schedules the expired session cleanup every SESSION_CLEANUP_INTERVAL.
+// Every gateway instance registers the task, asynq.Unique leaves one of them per interval.
+type SessionJobs struct {
+	scheduler *asynq.Scheduler
+	server    *asynq.Server
+	mux       *asynq.ServeMux
+	logger    zerolog.Logger
+}
+
+func NewSessionJobs(
+	config config.GatewayMrc,
+	redisOpt asynq.RedisClientOpt,
+	sessionsUsecase *usecases.SessionsUsecase,
+	logger zerolog.Logger,
+) (*SessionJobs, error) {
+	taskScheduler := asynq.NewScheduler(redisOpt, &asynq.SchedulerOpts{Logger: scheduler.NewLogger(logger)})
+	_, err := taskScheduler.Register(
+		fmt.Sprintf("@every %s", config.SessionCleanupInterval),
+		asynq.NewTask(TaskCleanupSessions, nil),
+		asynq.Queue(QueueGateway),
+		asynq.MaxRetry(1),
+		asynq.Unique(config.SessionCleanupInterval),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	server := asynq.NewServer(redisOpt, asynq.Config{
+		Queues:      map[string]int{QueueGateway: 1},
+		Concurrency: 1,
+		Logger:      scheduler.NewLogger(logger),
+	})
+	mux := asynq.NewServeMux()
+	mux.Use(tracing.TaskMiddleware, scheduler.TaskLogger(logger), metrics.TaskMiddleware)
+	mux.HandleFunc(TaskCleanupSessions, func(ctx context.Context, task *asynq.Task) error {
+		ended, purged, err := sessionsUsecase.CleanupSessions(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to clean up sessions: %w", err)
+		}
+		log.Ctx(ctx).Info().Int64("ended", ended).Int64("purged", purged).Msg("cleaned up sessions")
+		stats, _, err := sessionsUsecase.GetStats(ctx)
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("cannot get session stats")
+			return nil
+		}
+		metrics.SessionsActive.Set(float64(stats.ActiveSessions))
+		return nil
+	})
+	return &SessionJobs{scheduler: taskScheduler, server: server, mux: mux, logger: logger}, nil
+}
+
+func (jobs *SessionJobs) Start() error {
+	jobs.logger.Info().Msg("start session jobs")
+	if err := jobs.server.Start(jobs.mux); err != nil {
+		return err
+	}
+	if err := jobs.scheduler.Start(); err != nil {
+		jobs.server.Shutdown()
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops scheduling first, then waits for the running cleanup
+func (jobs *SessionJobs) Shutdown() {
+	jobs.scheduler.Shutdown()
+	jobs.server.Shutdown()
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"job_search_platform/internal/gateway_mrc/sessions"
+	"job_search_platform/pkg/helpers/server"
+	"net/http"
+)
+
+const (
+	CsrfTokenHeader = "X-CSRF-Token"
+	ApiKeyHeader    = "X-API-Key"
+)
+
+// CheckCsrf compares the X-CSRF-Token header with the token of the session on unsafe methods,
+// it returns false when the request was aborted.
+// Requests without a cookie session are not checked, X-API-Key or Authorization do not exempt
+// a request with a session: the gateway authenticates it by the cookie anyway.
+func CheckCsrf(ctx *gin.Context) bool {
+	if IsSafeMethod(ctx.Request.Method) {
+		return true
+	}
+	session, exists := sessions.FromContext(ctx)
+	if !exists {
+		return true
+	}
+	expected := sessions.ParseData(session.SessionData.String).CsrfToken
+	token := ctx.GetHeader(CsrfTokenHeader)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		server.AbortErr(ctx, server.CSRF_TOKEN_ERR_CODE, nil)
+		return false
+	}
+	return true
+}
+
+// IsSafeMethod reports whether the method is not checked for the CSRF token
+func IsSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func CsrfMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if CheckCsrf(ctx) {
+			ctx.Next()
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/jwt_token"
+)
+
+// RoleMiddleware lets through users that have the role, it must run after AuthMiddleware
+func RoleMiddleware(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		jwtPayload, exists := jwt_token.GetJWTPayload(ctx)
+		if exists {
+			for _, group := range jwtPayload.Groups {
+				if group == role {
+					ctx.Next()
+					return
+				}
+			}
+		}
+		server.AbortErr(ctx, server.PERMISSION_DENIED_ERR_CODE, nil)
+	}
+}
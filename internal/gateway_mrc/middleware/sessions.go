@@ -2,41 +2,47 @@ package middleware
 
 import (
 	"github.com/gin-gonic/gin"
-	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"github.com/rs/zerolog/log"
+	"job_search_platform/internal/gateway_mrc/sessions"
+	"job_search_platform/internal/gateway_mrc/usecases"
 	"job_search_platform/pkg/helpers/server"
-	"net/http"
+	"job_search_platform/pkg/logger"
 )
 
-func SessionMiddleware(store db.Store) gin.HandlerFunc {
+// SessionMiddleware loads the session from the cookie. Requests without a cookie stay anonymous,
+// the session is created only by the handlers that need it.
+func SessionMiddleware(usecase usecases.SessionsUsecase, cookie sessions.Cookie) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		uuidSession, err := ctx.Cookie("session_id")
-		if err != nil {
-			uuidSession = ""
+		sessionIdStr, err := cookie.Value(ctx)
+		if err != nil || sessionIdStr == "" {
+			ctx.Next()
+			return
 		}
-		userAgent := ctx.Request.UserAgent()
-		clientIp := ctx.ClientIP()
-		sessionArgs := db.RequestArgs{
-			IpAddress: clientIp,
-			UserAgent: userAgent,
+		session, errCode, err := usecase.GetSession(ctx, sessionIdStr)
+		if errCode == server.SESSION_NOT_FOUND_ERR_CODE || errCode == server.SESSION_PARSING_ERR_CODE ||
+			errCode == server.SESSION_EXPIRED_ERR_CODE {
+			// сессия истекла или cookie подделан, продолжаем как аноним
+			cookie.Clear(ctx)
+			ctx.Next()
+			return
 		}
-		session, created, errCode, err := store.GetOrCreateClientSession(ctx, uuidSession, sessionArgs)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, server.Response(err, errCode, nil))
+			server.AuthHandlerErr(ctx, errCode, err)
 			return
 		}
-		if !created && session.IsBlocked.Bool {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, server.Response(err, server.SESSION_BLOCKED_ERR_CODE, nil))
+		if session.IsBlocked.Bool {
+			server.AuthHandlerErr(ctx, server.SESSION_BLOCKED_ERR_CODE, nil)
 			return
 		}
-		if !created {
-			_, err = store.UpdateSessionLastActive(ctx, uuidSession)
+		touched, _, err := usecase.Touch(ctx, &session)
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("session", logger.Fingerprint(sessionIdStr)).Msg("cannot update session last active")
 		}
-		SessionId, _ := session.ID.Value()
-		if created {
-			ctx.SetCookie("session_id", SessionId.(string), 60*60*24, "/", "localhost", false, false)
+		if touched {
+			// продлеваем cookie вместе с сессией
+			cookie.Set(ctx, sessionIdStr)
 		}
-		//ctx.Set()
-		ctx.Set("sessionId", SessionId.(string))
+		sessions.SetContext(ctx, session)
 		ctx.Next()
 	}
 }
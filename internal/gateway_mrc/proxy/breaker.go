@@ -0,0 +1,177 @@
+package proxy
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (state BreakerState) String() string {
+	switch state {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+func (state BreakerState) MarshalText() ([]byte, error) {
+	return []byte(state.String()), nil
+}
+
+type BreakerSettings struct {
+	// FailureThreshold consecutive failures open the circuit
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before trial requests are let through
+	OpenTimeout time.Duration
+	// HalfOpenRequests successful trial requests close the circuit again
+	HalfOpenRequests int
+}
+
+// CircuitBreaker stops sending requests to an upstream that keeps failing
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	settings  BreakerSettings
+	state     BreakerState
+	failures  int
+	successes int
+	inFlight  int
+	openedAt  time.Time
+}
+
+func NewCircuitBreaker(settings BreakerSettings) *CircuitBreaker {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	if settings.HalfOpenRequests < 1 {
+		settings.HalfOpenRequests = 1
+	}
+	return &CircuitBreaker{settings: settings}
+}
+
+// Allow reports whether a request may be sent, every allowed request must be followed by Report
+func (breaker *CircuitBreaker) Allow() error {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	if breaker.state == StateOpen && time.Since(breaker.openedAt) >= breaker.settings.OpenTimeout {
+		breaker.state = StateHalfOpen
+		breaker.successes = 0
+		breaker.inFlight = 0
+	}
+	switch breaker.state {
+	case StateOpen:
+		return ErrCircuitOpen
+	case StateHalfOpen:
+		if breaker.inFlight >= breaker.settings.HalfOpenRequests {
+			return ErrCircuitOpen
+		}
+		breaker.inFlight++
+	}
+	return nil
+}
+
+func (breaker *CircuitBreaker) Report(success bool) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	switch breaker.state {
+	case StateHalfOpen:
+		breaker.inFlight--
+		if !success {
+			breaker.open()
+			return
+		}
+		breaker.successes++
+		if breaker.successes >= breaker.settings.HalfOpenRequests {
+			breaker.state = StateClosed
+			breaker.failures = 0
+		}
+	case StateClosed:
+		if success {
+			breaker.failures = 0
+			return
+		}
+		breaker.failures++
+		if breaker.failures >= breaker.settings.FailureThreshold {
+			breaker.open()
+		}
+	}
+}
+
+func (breaker *CircuitBreaker) open() {
+	breaker.state = StateOpen
+	breaker.openedAt = time.Now()
+	breaker.failures = 0
+	breaker.successes = 0
+	breaker.inFlight = 0
+}
+
+type BreakerStatus struct {
+	Upstream string       `json:"upstream"`
+	State    BreakerState `json:"state"`
+	Failures int          `json:"failures"`
+	OpenedAt *time.Time   `json:"opened_at,omitempty"`
+}
+
+func (breaker *CircuitBreaker) Status(upstream string) BreakerStatus {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	status := BreakerStatus{Upstream: upstream, State: breaker.state, Failures: breaker.failures}
+	if breaker.state != StateClosed {
+		openedAt := breaker.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// Breakers keeps one circuit breaker per upstream instance
+type Breakers struct {
+	mu       sync.Mutex
+	settings BreakerSettings
+	items    map[string]*CircuitBreaker
+}
+
+func NewBreakers(settings BreakerSettings) *Breakers {
+	return &Breakers{settings: settings, items: make(map[string]*CircuitBreaker)}
+}
+
+func (breakers *Breakers) Get(upstream string) *CircuitBreaker {
+	breakers.mu.Lock()
+	defer breakers.mu.Unlock()
+	breaker, ok := breakers.items[upstream]
+	if !ok {
+		breaker = NewCircuitBreaker(breakers.settings)
+		breakers.items[upstream] = breaker
+	}
+	return breaker
+}
+
+func (breakers *Breakers) Statuses() []BreakerStatus {
+	breakers.mu.Lock()
+	upstreams := make([]string, 0, len(breakers.items))
+	for upstream := range breakers.items {
+		upstreams = append(upstreams, upstream)
+	}
+	breakers.mu.Unlock()
+
+	sort.Strings(upstreams)
+	statuses := make([]BreakerStatus, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		statuses = append(statuses, breakers.Get(upstream).Status(upstream))
+	}
+	return statuses
+}
@@ -0,0 +1,22 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"job_search_platform/internal/gateway_mrc/routing"
+	"job_search_platform/pkg/helpers/server"
+	"net"
+	"net/http"
+)
+
+// ErrorStatus maps an upstream error to the response status and error code
+func ErrorStatus(err error) (int, int32) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrCircuitOpen), errors.Is(err, routing.ErrNoInstances):
+		return http.StatusServiceUnavailable, server.UPSTREAM_UNAVAILABLE_ERR_CODE
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusGatewayTimeout, server.UPSTREAM_TIMEOUT_ERR_CODE
+	}
+	return http.StatusBadGateway, server.UPSTREAM_BAD_GATEWAY_ERR_CODE
+}
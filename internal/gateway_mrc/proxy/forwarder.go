@@ -0,0 +1,340 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"job_search_platform/internal/gateway_mrc/routing"
+	"job_search_platform/pkg/config"
+	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/metrics"
+	"job_search_platform/pkg/middleware"
+	"job_search_platform/pkg/tracing"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxReplayBodySize is the largest request body that is buffered to be able to retry the request
+const maxReplayBodySize = 64 << 10
+
+// hopHeaders are meaningful only for a single connection and must not be forwarded (RFC 9110, 7.6.1)
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Forwarder sends requests to upstream services through one shared transport and streams the bodies.
+// Every upstream instance has its own circuit breaker, idempotent requests are retried on another instance.
+type Forwarder struct {
+	transport    http.RoundTripper
+	registry     *routing.Registry
+	breakers     *Breakers
+	maxRetries   int
+	retryBackoff time.Duration
+	// identity signs the user of the request for the services, nil when GATEWAY_IDENTITY_HEADERS is off
+	identity *middleware.IdentitySigner
+}
+
+func NewForwarder(config config.GatewayMrc, registry *routing.Registry, identity *middleware.IdentitySigner) *Forwarder {
+	dialer := &net.Dialer{
+		Timeout:   config.ProxyDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          config.ProxyMaxIdleConns,
+		MaxIdleConnsPerHost:   config.ProxyMaxIdleConnsPerHost,
+		IdleConnTimeout:       config.ProxyIdleConnTimeout,
+		TLSHandshakeTimeout:   config.ProxyDialTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	breakers := NewBreakers(BreakerSettings{
+		FailureThreshold: config.BreakerFailureThreshold,
+		OpenTimeout:      config.BreakerOpenTimeout,
+		HalfOpenRequests: config.BreakerHalfOpenRequests,
+	})
+	return &Forwarder{
+		transport:    &tracing.Transport{Base: transport},
+		registry:     registry,
+		breakers:     breakers,
+		maxRetries:   config.ProxyMaxRetries,
+		retryBackoff: config.ProxyRetryBackoff,
+		identity:     identity,
+	}
+}
+
+func (forwarder *Forwarder) Breakers() *Breakers {
+	return forwarder.breakers
+}
+
+// Forward sends the incoming request to an instance of service, prepare may add headers to every attempt.
+// Idempotent requests are retried with a jittered backoff on connection errors and 502/503/504,
+// the request deadline (see RouteHandler) bounds all attempts together.
+func (forwarder *Forwarder) Forward(ctx *gin.Context, service string, prepare func(req *http.Request)) (*http.Response, error) {
+	in := ctx.Request
+	attempts := 1
+	var replay []byte
+	if forwarder.maxRetries > 0 && isIdempotent(in.Method) {
+		switch {
+		case in.ContentLength == 0:
+			attempts += forwarder.maxRetries
+		case in.ContentLength > 0 && in.ContentLength <= maxReplayBodySize:
+			body, err := io.ReadAll(in.Body)
+			if err != nil {
+				return nil, err
+			}
+			replay = body
+			attempts += forwarder.maxRetries
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := forwarder.wait(in.Context(), attempt); err != nil {
+				return nil, errors.Join(err, lastErr)
+			}
+		}
+		target, err := forwarder.registry.Resolve(service)
+		if err != nil {
+			return nil, err
+		}
+		breaker := forwarder.breakers.Get(target)
+		if err = breaker.Allow(); err != nil {
+			lastErr = err
+			continue
+		}
+
+		var body io.Reader
+		if replay != nil {
+			body = bytes.NewReader(replay)
+		} else if in.ContentLength != 0 {
+			body = in.Body
+		}
+		req, err := forwarder.newRequest(ctx, target, body)
+		if err != nil {
+			breaker.Report(true)
+			return nil, err
+		}
+		if prepare != nil {
+			prepare(req)
+		}
+		if forwarder.identity != nil {
+			jwtPayload, _ := jwt_token.GetJWTPayload(ctx)
+			forwarder.identity.Sign(req, jwtPayload)
+		}
+
+		start := time.Now()
+		resp, err := forwarder.transport.RoundTrip(req)
+		observeUpstream(service, resp, start)
+		if err != nil {
+			// отмена запроса клиентом не говорит о состоянии upstream
+			breaker.Report(errors.Is(in.Context().Err(), context.Canceled))
+			lastErr = err
+			if in.Context().Err() != nil {
+				break
+			}
+			continue
+		}
+		if !isUnavailableStatus(resp.StatusCode) {
+			breaker.Report(true)
+			return resp, nil
+		}
+		breaker.Report(false)
+		if attempt == attempts-1 {
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxReplayBodySize))
+		resp.Body.Close()
+		lastErr = nil
+	}
+	if lastErr == nil {
+		lastErr = ErrCircuitOpen
+	}
+	return nil, lastErr
+}
+
+// Do sends a request made by the gateway itself to an instance of service through the breaker of the instance,
+// newRequest builds it for the base url of the instance. It is not retried.
+func (forwarder *Forwarder) Do(
+	ctx context.Context, service string, newRequest func(target string) (*http.Request, error)) (*http.Response, error) {
+	target, err := forwarder.registry.Resolve(service)
+	if err != nil {
+		return nil, err
+	}
+	breaker := forwarder.breakers.Get(target)
+	if err = breaker.Allow(); err != nil {
+		return nil, err
+	}
+	req, err := newRequest(target)
+	if err != nil {
+		breaker.Report(true)
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := forwarder.transport.RoundTrip(req.WithContext(ctx))
+	observeUpstream(service, resp, start)
+	switch {
+	case err != nil:
+		breaker.Report(errors.Is(ctx.Err(), context.Canceled))
+	default:
+		breaker.Report(!isUnavailableStatus(resp.StatusCode))
+	}
+	return resp, err
+}
+
+func observeUpstream(service string, resp *http.Response, start time.Time) {
+	status := "error"
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.UpstreamRequests.WithLabelValues(service, status).Inc()
+	metrics.UpstreamRequestDuration.WithLabelValues(service).Observe(time.Since(start).Seconds())
+}
+
+// wait sleeps for an exponential backoff with full jitter
+func (forwarder *Forwarder) wait(ctx context.Context, attempt int) error {
+	backoff := forwarder.retryBackoff << (attempt - 1)
+	if backoff <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(rand.N(backoff) + 1)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newRequest builds the upstream request for the incoming one, the body is streamed and not buffered
+func (forwarder *Forwarder) newRequest(ctx *gin.Context, target string, body io.Reader) (*http.Request, error) {
+	in := ctx.Request
+	url := target + in.URL.Path
+	if in.URL.RawQuery != "" {
+		url += "?" + in.URL.RawQuery
+	}
+	out, err := http.NewRequestWithContext(in.Context(), in.Method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	out.ContentLength = in.ContentLength
+	out.Header = in.Header.Clone()
+	removeHopHeaders(out.Header)
+	// заголовки пользователя выставляет только gateway
+	middleware.StripIdentityHeaders(out.Header)
+
+	out.Header.Del("X-Real-IP")
+	if clientIp := ctx.RemoteIP(); clientIp != "" {
+		// цепочку принимаем только от доверенного прокси, тогда gin берет из нее адрес клиента
+		if prior := out.Header.Values("X-Forwarded-For"); len(prior) > 0 && ctx.ClientIP() != clientIp {
+			clientIp = strings.Join(prior, ", ") + ", " + clientIp
+		}
+		out.Header.Set("X-Forwarded-For", clientIp)
+	}
+	proto := "http"
+	if in.TLS != nil {
+		proto = "https"
+	}
+	out.Header.Set("X-Forwarded-Proto", proto)
+	out.Header.Set("X-Forwarded-Host", in.Host)
+	if requestId := middleware.GetRequestId(ctx); requestId != "" {
+		out.Header.Set(middleware.RequestIdHeader, requestId)
+	}
+	if deadline, ok := in.Context().Deadline(); ok {
+		out.Header.Set(middleware.DeadlineHeader, deadline.UTC().Format(time.RFC3339Nano))
+	}
+	return out, nil
+}
+
+// CopyResponse writes the upstream status, end-to-end headers and body to the client.
+// Responses of unknown length (streams, server-sent events) are flushed after every write.
+// The server has no WriteTimeout, the write deadline is the route deadline, so a stream lasts
+// as long as the route allows and a client that stops reading does not hold the connection forever.
+func (forwarder *Forwarder) CopyResponse(ctx *gin.Context, resp *http.Response) error {
+	if deadline, ok := ctx.Request.Context().Deadline(); ok {
+		err := http.NewResponseController(ctx.Writer).SetWriteDeadline(deadline)
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	header := ctx.Writer.Header()
+	removeHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		// CORS заголовки выставляет сам gateway
+		if strings.HasPrefix(key, "Access-Control-") {
+			continue
+		}
+		header[key] = append([]string(nil), values...)
+	}
+	ctx.Status(resp.StatusCode)
+	ctx.Writer.WriteHeaderNow()
+
+	var dst io.Writer = ctx.Writer
+	if resp.ContentLength == -1 {
+		dst = &flushWriter{writer: ctx.Writer}
+	}
+	buf := make([]byte, 32*1024)
+	_, err := io.CopyBuffer(dst, resp.Body, buf)
+	if err != nil && ctx.Request.Context().Err() == context.Canceled {
+		// клиент закрыл соединение
+		return nil
+	}
+	return err
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isUnavailableStatus(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+func removeHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, field := range strings.Split(value, ",") {
+			if field = textproto.TrimString(field); field != "" {
+				header.Del(field)
+			}
+		}
+	}
+	for _, key := range hopHeaders {
+		header.Del(key)
+	}
+}
+
+type flushWriter struct {
+	writer gin.ResponseWriter
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.writer.Write(p)
+	if n > 0 {
+		fw.writer.Flush()
+	}
+	return n, err
+}
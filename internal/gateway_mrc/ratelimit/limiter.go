@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const keyPrefix = "ratelimit"
+
+// Limit is a token bucket: Rate tokens are added every Period, up to Burst tokens are kept
+type Limit struct {
+	Rate   int           `yaml:"rate"`
+	Period time.Duration `yaml:"period"`
+	Burst  int           `yaml:"burst"`
+}
+
+func (limit *Limit) Validate() error {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return errors.New("rate and period must be positive")
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Rate
+	}
+	return nil
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// tokenBucket refills and takes a token atomically, the redis clock is used so every gateway instance agrees
+var tokenBucket = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / period)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) * period / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * period / rate) + 1000)
+return {allowed, math.floor(tokens), retry, math.ceil((burst - tokens) * period / rate)}
+`)
+
+type RedisLimiter struct {
+	client redis.UniversalClient
+}
+
+func NewRedisLimiter(client redis.UniversalClient) *RedisLimiter {
+	return &RedisLimiter{client: client}
+}
+
+func (limiter *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := tokenBucket.Run(ctx, limiter.client, []string{keyPrefix + ":" + key},
+		limit.Rate, limit.Period.Milliseconds(), limit.Burst).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script failed: %w", err)
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrNoInstances = errors.New("no upstream instances")
+
+// ServiceResolver returns the instances of a service that are not listed in the route table
+type ServiceResolver func(service string) []string
+
+type upstream struct {
+	instances []string
+	next      atomic.Uint64
+}
+
+// Registry keeps upstream instances of every service and balances requests between them with round-robin
+type Registry struct {
+	mu       sync.RWMutex
+	services map[string]*upstream
+}
+
+func NewRegistry() *Registry {
+	return &Registry{services: make(map[string]*upstream)}
+}
+
+// Update replaces the registered services, instances listed in the table win over the resolver
+func (registry *Registry) Update(services map[string]Service, resolve ServiceResolver) {
+	updated := make(map[string]*upstream, len(services))
+	for name, service := range services {
+		instances := service.Instances
+		if len(instances) == 0 && resolve != nil {
+			instances = resolve(name)
+		}
+		updated[name] = &upstream{instances: normalizeInstances(instances)}
+	}
+
+	registry.mu.Lock()
+	registry.services = updated
+	registry.mu.Unlock()
+}
+
+// Resolve returns the base url of the next instance of the service
+func (registry *Registry) Resolve(service string) (string, error) {
+	registry.mu.RLock()
+	upstream, ok := registry.services[service]
+	registry.mu.RUnlock()
+	if !ok || len(upstream.instances) == 0 {
+		return "", fmt.Errorf("service %q: %w", service, ErrNoInstances)
+	}
+	idx := upstream.next.Add(1) - 1
+	return upstream.instances[idx%uint64(len(upstream.instances))], nil
+}
+
+// Instances returns a copy of the registered instances of the service
+func (registry *Registry) Instances(service string) []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	upstream, ok := registry.services[service]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), upstream.instances...)
+}
+
+func normalizeInstances(instances []string) []string {
+	result := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		instance = strings.TrimRight(strings.TrimSpace(instance), "/")
+		if instance != "" {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// reloadDelay merges the burst of events editors produce on a single save
+const reloadDelay = 200 * time.Millisecond
+
+// configMapData is the symlink a Kubernetes ConfigMap volume swaps on update,
+// routes.yaml itself is a symlink through it and gets no events
+const configMapData = "..data"
+
+// RouteTable holds the active route table and keeps the registry in sync with it
+type RouteTable struct {
+	path     string
+	current  atomic.Pointer[Table]
+	registry *Registry
+	resolve  ServiceResolver
+	logger   zerolog.Logger
+}
+
+func NewRouteTable(path string, registry *Registry, resolve ServiceResolver, logger zerolog.Logger) (*RouteTable, error) {
+	routeTable := &RouteTable{
+		path:     path,
+		registry: registry,
+		resolve:  resolve,
+		logger:   logger,
+	}
+	if err := routeTable.Reload(); err != nil {
+		return nil, err
+	}
+	return routeTable, nil
+}
+
+func (routeTable *RouteTable) Current() *Table {
+	return routeTable.current.Load()
+}
+
+func (routeTable *RouteTable) Registry() *Registry {
+	return routeTable.registry
+}
+
+// Reload reads the table again, on error the active table is kept
+func (routeTable *RouteTable) Reload() error {
+	table, err := LoadTable(routeTable.path)
+	if err != nil {
+		return err
+	}
+	routeTable.registry.Update(table.Services, routeTable.resolve)
+	routeTable.current.Store(table)
+	return nil
+}
+
+// Watch reloads the table when its file changes until ctx is done.
+// The directory is watched because editors replace the file instead of writing to it,
+// a config map update is seen as the create of its ..data symlink.
+func (routeTable *RouteTable) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("cannot create route table watcher: %w", err)
+	}
+	if err = watcher.Add(filepath.Dir(routeTable.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("cannot watch route table: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		var timer *time.Timer
+		reload := make(chan struct{}, 1)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !routeTable.affects(event.Name) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() {
+					select {
+					case reload <- struct{}{}:
+					default:
+					}
+				})
+			case <-reload:
+				if err := routeTable.Reload(); err != nil {
+					routeTable.logger.Error().Err(err).Str("path", routeTable.path).Msg("route table reload failed")
+					continue
+				}
+				routeTable.logger.Info().Str("path", routeTable.path).
+					Int("routes", len(routeTable.Current().Routes)).Msg("route table reloaded")
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				routeTable.logger.Error().Err(err).Msg("route table watcher error")
+			}
+		}
+	}()
+	return nil
+}
+
+// affects tells whether a change of name in the watched directory may change the table
+func (routeTable *RouteTable) affects(name string) bool {
+	name = filepath.Clean(name)
+	return name == filepath.Clean(routeTable.path) ||
+		name == filepath.Join(filepath.Dir(routeTable.path), configMapData)
+}
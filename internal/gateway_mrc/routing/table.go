@@ -0,0 +1,253 @@
+package routing
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"job_search_platform/internal/gateway_mrc/ratelimit"
+	"job_search_platform/pkg/validation"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type AuthType string
+
+const (
+	AuthPublic     AuthType = "public"
+	AuthSession    AuthType = "session"
+	AuthPermission AuthType = "permission"
+)
+
+const (
+	HandlerProxy  = "proxy"
+	HandlerSignIn = "sign_in"
+	HandlerLogout = "logout"
+	// HandlerCsrfToken is served by the gateway itself
+	HandlerCsrfToken = "csrf_token"
+)
+
+const (
+	defaultTimeout   = 10 * time.Second
+	defaultBodyLimit = 1 << 20
+
+	// DefaultRateLimitClass is applied to routes without rate_limit when the table defines it
+	DefaultRateLimitClass = "default"
+)
+
+// Rate limit keys
+const (
+	LimitByIp      = "ip"
+	LimitBySession = "session"
+	LimitByUser    = "user"
+	LimitByApiKey  = "api_key"
+)
+
+// defaultRoutes is used when no route table file is configured
+//
+//go:embed routes.yaml
+var defaultRoutes []byte
+
+type Route struct {
+	Prefix    string        `yaml:"prefix"`
+	Service   string        `yaml:"service"`
+	Methods   []string      `yaml:"methods"`
+	Auth      AuthType      `yaml:"auth"`
+	Roles     []string      `yaml:"roles"`
+	Handler   string        `yaml:"handler"`
+	Timeout   time.Duration `yaml:"timeout"`
+	BodyLimit int64         `yaml:"body_limit"`
+	RateLimit string        `yaml:"rate_limit"`
+	// CreateSession starts a session for anonymous clients, sign_in routes always do
+	CreateSession bool `yaml:"create_session"`
+	// SkipCsrf turns off the CSRF check, for the requests which can not carry the token (one-click unsubscribe)
+	SkipCsrf bool `yaml:"skip_csrf"`
+	// Validate holds the rules of the top level JSON body fields, checked at the edge before proxying
+	Validate map[string]string `yaml:"validate"`
+}
+
+type RouteDefaults struct {
+	Timeout   time.Duration `yaml:"timeout"`
+	BodyLimit int64         `yaml:"body_limit"`
+}
+
+type Service struct {
+	Instances []string `yaml:"instances"`
+}
+
+// RateLimitClass holds the limits of a group of routes by every key, a missing key is not limited
+type RateLimitClass map[string]ratelimit.Limit
+
+// Table is an immutable, validated route table. Routes are sorted from the longest prefix.
+type Table struct {
+	Defaults   RouteDefaults             `yaml:"defaults"`
+	Services   map[string]Service        `yaml:"services"`
+	RateLimits map[string]RateLimitClass `yaml:"rate_limits"`
+	Routes     []Route                   `yaml:"routes"`
+}
+
+// LoadTable reads the route table from path, the bundled table is used when path is empty.
+// A configured file that does not exist is an error, not a fallback to the bundled routes.
+func LoadTable(path string) (*Table, error) {
+	if path == "" {
+		return ParseTable(defaultRoutes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read route table: %w", err)
+	}
+	return ParseTable(data)
+}
+
+func ParseTable(data []byte) (*Table, error) {
+	table := &Table{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(table); err != nil {
+		return nil, fmt.Errorf("cannot parse route table: %w", err)
+	}
+	if table.Defaults.Timeout <= 0 {
+		table.Defaults.Timeout = defaultTimeout
+	}
+	if table.Defaults.BodyLimit <= 0 {
+		table.Defaults.BodyLimit = defaultBodyLimit
+	}
+
+	for name, class := range table.RateLimits {
+		for key, limit := range class {
+			switch key {
+			case LimitByIp, LimitBySession, LimitByUser, LimitByApiKey:
+			default:
+				return nil, fmt.Errorf("rate limit %q: unknown key %q", name, key)
+			}
+			if err := limit.Validate(); err != nil {
+				return nil, fmt.Errorf("rate limit %q.%s: %w", name, key, err)
+			}
+			class[key] = limit
+		}
+	}
+
+	for i := range table.Routes {
+		route := &table.Routes[i]
+		if err := table.normalize(route); err != nil {
+			return nil, fmt.Errorf("route %q: %w", route.Prefix, err)
+		}
+	}
+	sort.SliceStable(table.Routes, func(i, j int) bool {
+		return len(table.Routes[i].Prefix) > len(table.Routes[j].Prefix)
+	})
+	return table, nil
+}
+
+func (table *Table) normalize(route *Route) error {
+	if !strings.HasPrefix(route.Prefix, "/") {
+		return errors.New("prefix must start with /")
+	}
+	if len(route.Prefix) > 1 {
+		route.Prefix = strings.TrimSuffix(route.Prefix, "/")
+	}
+	if route.Handler == "" {
+		route.Handler = HandlerProxy
+	}
+	switch route.Handler {
+	case HandlerProxy, HandlerSignIn, HandlerLogout, HandlerCsrfToken:
+	default:
+		return fmt.Errorf("unknown handler %q", route.Handler)
+	}
+	_, ok := table.Services[route.Service]
+	if !ok && route.Handler != HandlerCsrfToken {
+		return fmt.Errorf("unknown service %q", route.Service)
+	}
+
+	switch route.Auth {
+	case AuthPublic, AuthSession:
+	case AuthPermission:
+		if len(route.Roles) == 0 {
+			return errors.New("auth permission requires roles")
+		}
+	default:
+		return fmt.Errorf("unknown auth %q", route.Auth)
+	}
+
+	for i, method := range route.Methods {
+		method = strings.ToUpper(method)
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		default:
+			return fmt.Errorf("unknown method %q", method)
+		}
+		route.Methods[i] = method
+	}
+
+	if route.Timeout <= 0 {
+		route.Timeout = table.Defaults.Timeout
+	}
+	if route.BodyLimit <= 0 {
+		route.BodyLimit = table.Defaults.BodyLimit
+	}
+	if route.RateLimit == "" {
+		if _, ok := table.RateLimits[DefaultRateLimitClass]; ok {
+			route.RateLimit = DefaultRateLimitClass
+		}
+	} else if _, ok := table.RateLimits[route.RateLimit]; !ok {
+		return fmt.Errorf("unknown rate limit %q", route.RateLimit)
+	}
+	if err := rules.CheckRules(route.Validate); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+	return nil
+}
+
+// rules checks the validate rules of the routes, the tags are the same as in the RouteHandler
+var rules = validation.New()
+
+// HasBody reports whether the body of the method is validated
+func HasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// Match returns the route with the longest prefix that matches path and allows method
+func (table *Table) Match(method string, path string) (*Route, bool) {
+	for i := range table.Routes {
+		route := &table.Routes[i]
+		if route.matchesPath(path) && route.AllowsMethod(method) {
+			return route, true
+		}
+	}
+	return nil, false
+}
+
+func (route *Route) matchesPath(path string) bool {
+	if route.Prefix == "/" {
+		return true
+	}
+	return path == route.Prefix || strings.HasPrefix(path, route.Prefix+"/")
+}
+
+func (route *Route) AllowsMethod(method string) bool {
+	if len(route.Methods) == 0 {
+		return true
+	}
+	for _, allowed := range route.Methods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether one of the user roles is allowed by the route
+func (route *Route) HasRole(roles []string) bool {
+	for _, role := range roles {
+		for _, allowed := range route.Roles {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,35 +2,58 @@ package server
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	db "job_search_platform/internal/gateway_mrc/db/sqlc"
 	"job_search_platform/internal/gateway_mrc/handlers"
 	"job_search_platform/internal/gateway_mrc/middleware"
+	"job_search_platform/internal/gateway_mrc/proxy"
+	"job_search_platform/internal/gateway_mrc/ratelimit"
+	"job_search_platform/internal/gateway_mrc/routing"
+	"job_search_platform/internal/gateway_mrc/sessions"
 	"job_search_platform/internal/gateway_mrc/usecases"
+	"job_search_platform/pkg/app"
+	"job_search_platform/pkg/apperr"
 	"job_search_platform/pkg/config"
+	"job_search_platform/pkg/health"
+	server2 "job_search_platform/pkg/helpers/server"
 	"job_search_platform/pkg/jwt_token"
-	"log"
+	"job_search_platform/pkg/metrics"
+	middleware2 "job_search_platform/pkg/middleware"
+	"job_search_platform/pkg/tracing"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 	"time"
 )
 
 type Server struct {
-	config     config.Config
+	config     config.GatewayMrc
 	store      db.Store
+	redis      redis.UniversalClient
+	sessions   usecases.SessionsUsecase
 	router     *gin.Engine
 	tokenMaker jwt_token.Maker
+	routes     *routing.RouteTable
+	cookie     sessions.Cookie
+	identity   *middleware2.IdentitySigner
 	httpServer *http.Server
+	health     *health.Health
 	logger     zerolog.Logger
+
+	stopWatch context.CancelFunc
 }
 
-func NewServer(config config.Config, store db.Store, logger zerolog.Logger) (*Server, error) {
+func NewServer(
+	config config.GatewayMrc,
+	store db.Store,
+	redisClient redis.UniversalClient,
+	sessionsUsecase usecases.SessionsUsecase,
+	health *health.Health,
+	logger zerolog.Logger,
+) (*Server, error) {
 	tokenMaker, err := jwt_token.NewJWTMaker(
 		config.TokenSymmetricKey,
 		config.AccessTokenExpiresIn,
@@ -40,148 +63,166 @@ func NewServer(config config.Config, store db.Store, logger zerolog.Logger) (*Se
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	resolveService := func(service string) []string {
+		return strings.Split(config.ServiceUrl(service), ",")
+	}
+	routes, err := routing.NewRouteTable(config.GatewayRoutesFile, routing.NewRegistry(), resolveService, logger)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load route table: %w", err)
+	}
+
+	cookie, err := sessions.NewCookie(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session cookie config: %w", err)
+	}
+
+	var identity *middleware2.IdentitySigner
+	if config.GatewayIdentityHeaders {
+		identity, err = middleware2.NewIdentitySigner(config.GatewayIdentitySecret, config.GatewayIdentityMaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create identity signer: %w", err)
+		}
+	}
+
 	server := &Server{
 		config:     config,
 		store:      store,
+		redis:      redisClient,
+		sessions:   sessionsUsecase,
 		tokenMaker: tokenMaker,
+		routes:     routes,
+		cookie:     cookie,
+		identity:   identity,
+		health:     health,
 		logger:     logger,
+		stopWatch:  func() {},
+	}
+	if config.GatewayRoutesWatch && config.GatewayRoutesFile != "" {
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		if err = routes.Watch(watchCtx); err != nil {
+			stopWatch()
+			return nil, err
+		}
+		server.stopWatch = stopWatch
+	}
+	server.addUpstreamChecks()
+	if err = server.setupRouter(); err != nil {
+		return nil, err
 	}
-	server.setupRouter()
 
+	// без WriteTimeout: время ответа ограничивает timeout маршрута (RouteHandler), общий лимит сервера
+	// обрывал бы соединение раньше 504 и срезал бы timeout маршрутов больше него
 	server.httpServer = &http.Server{
 		Addr:           config.HTTPServerAddress,
 		Handler:        server.router,
 		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
 	return server, nil
 }
 
-func (server *Server) setupRouter() {
-	router := gin.Default()
+func (server *Server) setupRouter() error {
+	router := gin.New()
+	// контекст gin отдает значения контекста запроса, в том числе текущий span
+	router.ContextWithFallback = true
+	// без доверенных прокси ClientIP - адрес соединения, X-Forwarded-For клиента не учитывается
+	if err := router.SetTrustedProxies(server.config.TrustedProxies()); err != nil {
+		return fmt.Errorf("invalid GATEWAY_TRUSTED_PROXIES: %w", err)
+	}
 	// CORS
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{server.config.Origin}, // Укажите домен вашего клиента
 		AllowCredentials: true,                           // Разрешить использование учетных данных (например, куки)
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-CSRF-Token"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.CsrfTokenHeader, middleware2.RequestIdHeader, middleware.ApiKeyHeader},
+		ExposeHeaders: []string{
+			"Content-Disposition", middleware2.RequestIdHeader, middleware.CsrfTokenHeader,
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+		},
 	}
 
-	router.Use(gin.Recovery())
-	router.Use(gin.Logger())
+	router.Use(gin.CustomRecovery(func(ctx *gin.Context, recovered interface{}) {
+		apperr.Abort(ctx, apperr.Internal("panic", fmt.Errorf("%v", recovered)))
+	}))
+	router.Use(middleware2.RequestId())
+	router.Use(tracing.Middleware("gateway_mrc", metrics.Route))
+	router.Use(middleware2.RequestLogger(server.logger))
+	router.Use(metrics.Middleware("gateway_mrc"))
 	router.Use(cors.New(corsConfig))
 
-	// SESSION
-	sessionMiddleware := middleware.SessionMiddleware(server.store)
-	router.Use(sessionMiddleware)
-
 	router.NoRoute(func(ctx *gin.Context) {
-		ctx.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Route %s not found", ctx.Request.URL)})
+		server2.AbortErr(ctx, server2.ROUTE_NOT_FOUND_ERR_CODE, fmt.Errorf("route %s not found", ctx.Request.URL))
 	})
 	router.GET("/ping", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
-
-	//
-	usecase := usecases.NewSessionsUsecase(server.store)
-	handler := handlers.NewProxyHandler(server.tokenMaker, usecase, server.config)
-	server.setupAuthRoutes(router, handler)
-	server.setupUsersRoutes(router, handler)
-	server.router = router
-}
-
-func (server *Server) setupAuthRoutes(router *gin.Engine, handler handlers.ProxyHandler) {
-	authMiddleware := middleware.AuthMiddleware(server.tokenMaker, server.store, server.config)
-	public := router.Group("/api/v1/auth/public")
-	{
-		public.POST("/sign-up", func(ctx *gin.Context) {
-			handler.ProxyCommonReq(ctx, server.config.UsersMrcUrl)
-		})
-		public.POST("/sign-in", func(ctx *gin.Context) {
-			handler.ProxySignInReq(ctx, server.config.UsersMrcUrl)
-		})
-	}
-
-	private := router.Group("/api/v1/auth/private")
-	private.Use(authMiddleware)
-	{
-		private.GET("/logout", func(ctx *gin.Context) {
-			handler.ProxyLogoutReq(ctx)
-		})
-		private.POST("/*path", func(ctx *gin.Context) {
-			handler.ProxyCommonReq(ctx, server.config.UsersMrcUrl)
-		})
-		private.DELETE("/*path", func(ctx *gin.Context) {
-			handler.ProxyCommonReq(ctx, server.config.UsersMrcUrl)
-		})
-		private.PUT("/*path", func(ctx *gin.Context) {
-			handler.ProxyCommonReq(ctx, server.config.UsersMrcUrl)
-		})
+	server.health.Register(router)
+
+	// маршруты к сервисам описаны в таблице GATEWAY_ROUTES_FILE
+	usecase := server.sessions
+	// SESSION, только для /api и /admin: /ping и прочее не читают хранилище сессий
+	sessionMiddleware := middleware.SessionMiddleware(usecase, server.cookie)
+	forwarder := proxy.NewForwarder(server.config, server.routes.Registry(), server.identity)
+	proxyHandler := handlers.NewProxyHandler(server.tokenMaker, usecase, server.config, forwarder, server.cookie)
+	// обновление access токена одно на сессию для всех ее параллельных запросов
+	refresher := usecases.NewTokenRefresher(&server.sessions, server.redis, server.tokenMaker, forwarder, server.config)
+	authenticate := middleware.Authenticate(server.tokenMaker, refresher)
+	var limiter ratelimit.Limiter
+	if server.config.RateLimitEnabled {
+		limiter = ratelimit.NewRedisLimiter(server.redis)
 	}
-}
-
-func (server *Server) setupUsersRoutes(router *gin.Engine, handler handlers.ProxyHandler) {
-	authMiddleware := middleware.AuthMiddleware(server.tokenMaker, server.store, server.config)
-	private := router.Group("/api/v1/users/private")
-	address := server.config.UsersMrcUrl
-	private.Use(authMiddleware)
-	{
-		registerRoutes(private, handler, address)
+	routeHandler := handlers.NewRouteHandler(server.routes, proxyHandler, authenticate, limiter)
+	router.Any("/api/*path", sessionMiddleware, routeHandler.Dispatch)
+
+	adminHandler := handlers.NewAdminHandler(server.routes, forwarder, usecase)
+	admin := router.Group("/admin")
+	admin.Use(sessionMiddleware)
+	admin.Use(middleware.CsrfMiddleware())
+	admin.Use(middleware.AuthMiddleware(server.tokenMaker, refresher))
+	admin.Use(middleware.RoleMiddleware(server.config.GatewayAdminRole))
+	admin.GET("/upstreams", adminHandler.GetUpstreams)
+	admin.GET("/sessions/stats", adminHandler.GetSessionStats)
+
+	if server.config.GatewayDocsEnabled {
+		docsHandler := handlers.NewDocsHandler(server.routes, server.cookie.Name, server.config.GatewayDocsCacheTTL)
+		router.GET("/docs", docsHandler.UI)
+		router.GET("/docs/openapi.json", docsHandler.Spec)
 	}
+	server.router = router
+	return nil
 }
 
-func registerRoutes(group *gin.RouterGroup, handler handlers.ProxyHandler, address string) {
-	group.GET("/*path", func(ctx *gin.Context) {
-		handler.ProxyCommonReq(ctx, address)
-	})
-	group.POST("/*path", func(ctx *gin.Context) {
-		handler.ProxyCommonReq(ctx, address)
-	})
-	group.DELETE("/*path", func(ctx *gin.Context) {
-		handler.ProxyCommonReq(ctx, address)
-	})
-	group.PUT("/*path", func(ctx *gin.Context) {
-		handler.ProxyCommonReq(ctx, address)
-	})
-}
-
-func (server *Server) Start() error {
-	go func() {
-		log.Printf("Starting server on %s\n", server.httpServer.Addr)
-		if err := server.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			server.logger.Fatal().Err(err).Msg("Failed to start server")
-		}
-	}()
-
-	// Ожидание сигналов завершения
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	<-quit
-	log.Println("Shutting down server...")
-
-	// Контекст с таймаутом для завершения
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := server.httpServer.Shutdown(ctx); err != nil {
-		server.logger.Fatal().Err(err).Msg("Server forced to shutdown")
+// addUpstreamChecks makes readiness depend on the services of the route table at start,
+// a service is up when one of its instances answers /healthz
+func (server *Server) addUpstreamChecks() {
+	client := &http.Client{}
+	registry := server.routes.Registry()
+	for name := range server.routes.Current().Services {
+		instances := func() []string { return registry.Instances(name) }
+		server.health.Add("upstream_"+name, health.Any(instances, func(instance string) health.CheckFunc {
+			return health.Http(client, instance+"/healthz")
+		}))
 	}
-
-	log.Println("Server exited gracefully")
-	return nil
 }
 
-func RunGinServer(config config.Config, store db.Store, logger zerolog.Logger) error {
-	server, err := NewServer(config, store, logger)
-	if err != nil {
-		return err
+// Hook runs the http server in the application. On stop /readyz turns 503 for SHUTDOWN_DRAIN_DELAY,
+// so that load balancers stop sending requests, then the active requests are finished.
+func (server *Server) Hook() app.Hook {
+	hook := app.HttpServer("http server", server.httpServer)
+	run := hook.OnRun
+	hook.OnRun = func(ctx context.Context) error {
+		server.logger.Info().Msgf("Starting server on %s", server.httpServer.Addr)
+		return run(ctx)
 	}
-
-	err = server.Start()
-	if err != nil {
-		return err
+	hook.OnStop = func(ctx context.Context) error {
+		server.health.SetDraining()
+		select {
+		case <-time.After(server.config.ShutdownDrainDelay):
+		case <-ctx.Done():
+		}
+		server.stopWatch()
+		return server.httpServer.Shutdown(ctx)
 	}
-	return nil
+	hook.StopTimeout = server.config.ShutdownDrainDelay + server.config.ShutdownTimeout
+	return hook
 }
@@ -0,0 +1,37 @@
+package sessions
+
+import (
+	"github.com/gin-gonic/gin"
+	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"job_search_platform/pkg/logger"
+)
+
+const (
+	sessionKey   = "session"
+	sessionIdKey = "sessionId"
+)
+
+// SetContext stores the session of the request, sessionId stays a string for the rate limiter and proxy headers
+func SetContext(ctx *gin.Context, session db.Session) {
+	id, _ := session.ID.Value()
+	ctx.Set(sessionKey, session)
+	ctx.Set(sessionIdKey, id.(string))
+	// id - значение cookie, в логи попадает только отпечаток
+	logger.Annotate(ctx, "session", logger.Fingerprint(id.(string)))
+}
+
+// FromContext returns the session loaded by SessionMiddleware, anonymous requests have none
+func FromContext(ctx *gin.Context) (db.Session, bool) {
+	value, exists := ctx.Get(sessionKey)
+	if !exists {
+		return db.Session{}, false
+	}
+	session, ok := value.(db.Session)
+	return session, ok
+}
+
+// ClearContext forgets the session of the request after it was ended
+func ClearContext(ctx *gin.Context) {
+	ctx.Set(sessionKey, nil)
+	ctx.Set(sessionIdKey, "")
+}
@@ -0,0 +1,81 @@
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"job_search_platform/pkg/config"
+	"net/http"
+	"strings"
+)
+
+const hostPrefix = "__Host-"
+
+// Cookie holds the session cookie attributes from SESSION_COOKIE_*, the max-age follows SESSION_DURATION
+type Cookie struct {
+	Name     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+	MaxAge   int
+}
+
+func NewCookie(config config.GatewayMrc) (Cookie, error) {
+	cookie := Cookie{
+		Name:     config.SessionCookieName,
+		Domain:   config.SessionCookieDomain,
+		Secure:   config.SessionCookieSecure,
+		HttpOnly: config.SessionCookieHttpOnly,
+		MaxAge:   config.SessionDuration * 24 * 60 * 60,
+	}
+	if cookie.Name == "" {
+		return cookie, errors.New("session cookie name is empty")
+	}
+	switch strings.ToLower(config.SessionCookieSameSite) {
+	case "lax", "":
+		cookie.SameSite = http.SameSiteLaxMode
+	case "strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	case "none":
+		if !cookie.Secure {
+			return cookie, errors.New("SameSite=None session cookie must be secure")
+		}
+		cookie.SameSite = http.SameSiteNoneMode
+	default:
+		return cookie, fmt.Errorf("unknown session cookie SameSite %q", config.SessionCookieSameSite)
+	}
+	if config.SessionCookieHostPrefix {
+		// браузер принимает __Host- cookie только с Secure, Path=/ и без Domain
+		if !cookie.Secure || cookie.Domain != "" {
+			return cookie, errors.New("__Host- session cookie must be secure and have no domain")
+		}
+		cookie.Name = hostPrefix + cookie.Name
+	}
+	return cookie, nil
+}
+
+func (cookie Cookie) Value(ctx *gin.Context) (string, error) {
+	return ctx.Cookie(cookie.Name)
+}
+
+func (cookie Cookie) Set(ctx *gin.Context, id string) {
+	cookie.write(ctx, id, cookie.MaxAge)
+}
+
+func (cookie Cookie) Clear(ctx *gin.Context) {
+	cookie.write(ctx, "", -1)
+}
+
+func (cookie Cookie) write(ctx *gin.Context, value string, maxAge int) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     cookie.Name,
+		Value:    value,
+		Path:     "/",
+		Domain:   cookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+		SameSite: cookie.SameSite,
+	})
+}
@@ -0,0 +1,24 @@
+package sessions
+
+import (
+	"encoding/json"
+)
+
+// Data is kept in the session_data column as json
+type Data struct {
+	CsrfToken string `json:"csrf_token,omitempty"`
+}
+
+// ParseData reads session_data, a broken value is treated as empty
+func ParseData(value string) Data {
+	var data Data
+	if value != "" {
+		_ = json.Unmarshal([]byte(value), &data)
+	}
+	return data
+}
+
+func (data Data) Encode() string {
+	encoded, _ := json.Marshal(data)
+	return string(encoded)
+}
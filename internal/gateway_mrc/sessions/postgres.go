@@ -0,0 +1,138 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"time"
+)
+
+// PostgresStore keeps the sessions in the sessions table, it is the durable option
+type PostgresStore struct {
+	store db.Store
+}
+
+func NewPostgresStore(store db.Store) *PostgresStore {
+	return &PostgresStore{store: store}
+}
+
+func (s *PostgresStore) Create(ctx context.Context, args CreateSessionParams) (db.Session, error) {
+	return s.store.CreateSession(ctx, db.CreateSessionParams{
+		UserAgent:    args.UserAgent,
+		ClientIp:     args.ClientIp,
+		ExpiresAt:    args.ExpiresAt,
+		AccessToken:  pgtype.Text{String: args.AccessToken, Valid: args.AccessToken != ""},
+		RefreshToken: pgtype.Text{String: args.RefreshToken, Valid: args.RefreshToken != ""},
+	})
+}
+
+func (s *PostgresStore) Get(ctx context.Context, id uuid.UUID) (db.Session, error) {
+	session, err := s.store.GetSession(ctx, pgID(id))
+	if err == nil && session.EndedAt.Valid {
+		return session, ErrSessionNotFound
+	}
+	return session, notFound(err)
+}
+
+func (s *PostgresStore) UpdateTokens(ctx context.Context, id uuid.UUID, accessToken, refreshToken string) error {
+	_, err := s.store.UpdateSessionData(ctx, db.UpdateSessionDataParams{
+		AccessToken:  pgtype.Text{String: accessToken, Valid: accessToken != ""},
+		RefreshToken: pgtype.Text{String: refreshToken, Valid: refreshToken != ""},
+		ID:           pgID(id),
+	})
+	return notFound(err)
+}
+
+func (s *PostgresStore) UpdateData(ctx context.Context, id uuid.UUID, data string) error {
+	_, err := s.store.UpdateSessionData(ctx, db.UpdateSessionDataParams{
+		SessionData: pgtype.Text{String: data, Valid: true},
+		ID:          pgID(id),
+	})
+	return notFound(err)
+}
+
+func (s *PostgresStore) Touch(ctx context.Context, id uuid.UUID, lastActive, expiresAt time.Time) error {
+	_, err := s.store.UpdateSessionData(ctx, db.UpdateSessionDataParams{
+		LastActive: pgtype.Timestamptz{Time: lastActive, Valid: true},
+		ExpiresAt:  pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		ID:         pgID(id),
+	})
+	return notFound(err)
+}
+
+func (s *PostgresStore) Block(ctx context.Context, id uuid.UUID) error {
+	_, err := s.store.BlockSession(ctx, db.BlockSessionParams{
+		IsBlocked: pgtype.Bool{Bool: true, Valid: true},
+		ID:        pgID(id),
+	})
+	return notFound(err)
+}
+
+func (s *PostgresStore) End(ctx context.Context, id uuid.UUID, endedAt time.Time) error {
+	return s.store.EndSession(ctx, db.EndSessionParams{
+		EndedAt: pgtype.Timestamptz{Time: endedAt, Valid: true},
+		ID:      pgID(id),
+	})
+}
+
+func (s *PostgresStore) EndExpired(ctx context.Context, now time.Time, limit int) (int64, error) {
+	return s.store.EndExpiredSessions(ctx, db.EndExpiredSessionsParams{
+		EndedAt: pgtype.Timestamptz{Time: now, Valid: true},
+		Limit:   int32(limit),
+	})
+}
+
+func (s *PostgresStore) PurgeEnded(ctx context.Context, before time.Time) (int64, error) {
+	return purgeEnded(ctx, s.store, before)
+}
+
+func (s *PostgresStore) Stats(ctx context.Context, now time.Time) (Stats, error) {
+	stats, err := endedStats(ctx, s.store)
+	if err != nil {
+		return stats, err
+	}
+	rows, err := s.store.CountActiveSessionsByUserAgent(ctx, now)
+	if err != nil {
+		return stats, err
+	}
+	for _, row := range rows {
+		stats.ActiveSessions += row.SessionsCount
+		stats.UserAgentFamilies[UserAgentFamily(row.UserAgent)] += row.SessionsCount
+	}
+	return stats, nil
+}
+
+func (s *PostgresStore) Active(ctx context.Context, now time.Time, limit int) ([]db.Session, error) {
+	return s.store.ListActiveSessions(ctx, db.ListActiveSessionsParams{
+		ExpiresAt: now,
+		Limit:     int32(limit),
+	})
+}
+
+func purgeEnded(ctx context.Context, store db.Store, before time.Time) (int64, error) {
+	return store.DeleteEndedSessions(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+}
+
+func endedStats(ctx context.Context, store db.Store) (Stats, error) {
+	stats := Stats{UserAgentFamilies: map[string]int64{}}
+	ended, err := store.GetEndedSessionsStats(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.EndedSessions = ended.EndedSessions
+	stats.AverageLengthSeconds = ended.AverageLengthSeconds
+	return stats, nil
+}
+
+func pgID(id uuid.UUID) pgtype.UUID {
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
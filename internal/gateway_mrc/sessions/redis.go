@@ -0,0 +1,348 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	keyPrefix = "session:"
+	// expiryKey orders the sessions by expires_at, members are <user agent family>:<id>
+	expiryKey = "sessions:expiry"
+	// familiesKey counts the sessions of every user agent family
+	familiesKey = "sessions:user_agents"
+
+	// endGrace keeps an expired hash until the cleanup job moves it to postgres
+	endGrace = time.Hour
+)
+
+// updateScript sets the hash fields only while the session exists, so an update racing
+// with the expiry does not resurrect the key without a TTL.
+// ARGV: new expiry in unix milliseconds or 0, grace in milliseconds, session id, field/value pairs.
+var updateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 4))
+local expiresAt = tonumber(ARGV[1])
+if expiresAt > 0 then
+	redis.call('PEXPIREAT', KEYS[1], expiresAt + tonumber(ARGV[2]))
+	local family = redis.call('HGET', KEYS[1], 'user_agent_family')
+	redis.call('ZADD', KEYS[2], expiresAt, family .. ':' .. ARGV[3])
+end
+return 1
+`)
+
+// endScript removes a session which expires not later than the given time and returns its fields for the archive.
+// ARGV: session id, max expiry in unix milliseconds, user agent family or empty to read it from the hash.
+var endScript = redis.NewScript(`
+local family = ARGV[3]
+if family == '' then
+	family = redis.call('HGET', KEYS[1], 'user_agent_family')
+	if not family then
+		return false
+	end
+end
+local member = family .. ':' .. ARGV[1]
+local score = redis.call('ZSCORE', KEYS[2], member)
+if not score or tonumber(score) > tonumber(ARGV[2]) then
+	return false
+end
+local fields = redis.call('HMGET', KEYS[1], 'user_agent', 'client_ip', 'last_active', 'expires_at', 'created_at')
+redis.call('DEL', KEYS[1])
+redis.call('ZREM', KEYS[2], member)
+redis.call('HINCRBY', KEYS[3], family, -1)
+return fields
+`)
+
+// statsScript counts the active sessions and their user agent families at one moment. The family counters
+// still include the expired sessions the cleanup has not ended yet, they are subtracted by their expiry members.
+// ARGV: now in unix milliseconds. Returns the active count and family/count pairs.
+var statsScript = redis.NewScript(`
+local active = redis.call('ZCOUNT', KEYS[1], ARGV[1], '+inf')
+local counts = {}
+local families = redis.call('HGETALL', KEYS[2])
+for i = 1, #families, 2 do
+	counts[families[i]] = tonumber(families[i + 1])
+end
+for _, member in ipairs(redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[1])) do
+	local family = string.match(member, '^([^:]*):')
+	if family and counts[family] then
+		counts[family] = counts[family] - 1
+	end
+end
+local result = {active}
+for family, count in pairs(counts) do
+	if count > 0 then
+		table.insert(result, family)
+		table.insert(result, count)
+	end
+end
+return result
+`)
+
+// RedisStore keeps every session in a hash which expires together with the session.
+// Ended sessions are archived to the postgres sessions table for the statistics.
+type RedisStore struct {
+	client  redis.UniversalClient
+	archive db.Store
+}
+
+func NewRedisStore(client redis.UniversalClient, archive db.Store) *RedisStore {
+	return &RedisStore{client: client, archive: archive}
+}
+
+func (s *RedisStore) Create(ctx context.Context, args CreateSessionParams) (db.Session, error) {
+	now := time.Now()
+	id := uuid.New()
+	family := UserAgentFamily(args.UserAgent)
+	session := db.Session{
+		ID:         pgtype.UUID{Bytes: id, Valid: true},
+		UserAgent:  args.UserAgent,
+		ClientIp:   args.ClientIp,
+		IsBlocked:  pgtype.Bool{Bool: false, Valid: true},
+		LastActive: pgtype.Timestamptz{Time: now, Valid: true},
+		ExpiresAt:  args.ExpiresAt,
+		CreatedAt:  pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	key := keyPrefix + id.String()
+	values := []interface{}{
+		"user_agent", args.UserAgent,
+		"user_agent_family", family,
+		"client_ip", args.ClientIp,
+		"is_blocked", "0",
+		"last_active", unixMilli(now),
+		"expires_at", unixMilli(args.ExpiresAt),
+		"created_at", unixMilli(now),
+	}
+	if args.AccessToken != "" {
+		session.AccessToken = text(args.AccessToken)
+		values = append(values, "access_token", args.AccessToken)
+	}
+	if args.RefreshToken != "" {
+		session.RefreshToken = text(args.RefreshToken)
+		values = append(values, "refresh_token", args.RefreshToken)
+	}
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, values...)
+		pipe.PExpireAt(ctx, key, args.ExpiresAt.Add(endGrace))
+		pipe.ZAdd(ctx, expiryKey, redis.Z{Score: float64(args.ExpiresAt.UnixMilli()), Member: family + ":" + id.String()})
+		pipe.HIncrBy(ctx, familiesKey, family, 1)
+		return nil
+	})
+	return session, err
+}
+
+func (s *RedisStore) Get(ctx context.Context, id uuid.UUID) (db.Session, error) {
+	var session db.Session
+	fields, err := s.client.HGetAll(ctx, keyPrefix+id.String()).Result()
+	if err != nil {
+		return session, err
+	}
+	if len(fields) == 0 {
+		return session, ErrSessionNotFound
+	}
+	session = db.Session{
+		ID:           pgtype.UUID{Bytes: id, Valid: true},
+		AccessToken:  text(fields["access_token"]),
+		RefreshToken: text(fields["refresh_token"]),
+		SessionData:  text(fields["session_data"]),
+		UserAgent:    fields["user_agent"],
+		ClientIp:     fields["client_ip"],
+		IsBlocked:    pgtype.Bool{Bool: fields["is_blocked"] == "1", Valid: true},
+		LastActive:   timestamp(fields["last_active"]),
+		ExpiresAt:    timestamp(fields["expires_at"]).Time,
+		CreatedAt:    timestamp(fields["created_at"]),
+	}
+	return session, nil
+}
+
+func (s *RedisStore) UpdateTokens(ctx context.Context, id uuid.UUID, accessToken, refreshToken string) error {
+	var values []interface{}
+	if accessToken != "" {
+		values = append(values, "access_token", accessToken)
+	}
+	if refreshToken != "" {
+		values = append(values, "refresh_token", refreshToken)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return s.update(ctx, id, time.Time{}, values...)
+}
+
+func (s *RedisStore) UpdateData(ctx context.Context, id uuid.UUID, data string) error {
+	return s.update(ctx, id, time.Time{}, "session_data", data)
+}
+
+func (s *RedisStore) Touch(ctx context.Context, id uuid.UUID, lastActive, expiresAt time.Time) error {
+	return s.update(ctx, id, expiresAt, "last_active", unixMilli(lastActive), "expires_at", unixMilli(expiresAt))
+}
+
+func (s *RedisStore) Block(ctx context.Context, id uuid.UUID) error {
+	return s.update(ctx, id, time.Time{}, "is_blocked", "1")
+}
+
+func (s *RedisStore) End(ctx context.Context, id uuid.UUID, endedAt time.Time) error {
+	_, err := s.end(ctx, id, "", math.MaxInt64, endedAt)
+	return err
+}
+
+func (s *RedisStore) EndExpired(ctx context.Context, now time.Time, limit int) (int64, error) {
+	members, err := s.client.ZRangeByScore(ctx, expiryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	var ended int64
+	for _, member := range members {
+		family, idStr, _ := strings.Cut(member, ":")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			s.client.ZRem(ctx, expiryKey, member)
+			continue
+		}
+		// сессию могли продлить после выборки, тогда она не завершается
+		ok, err := s.end(ctx, id, family, now.UnixMilli(), now)
+		if err != nil {
+			return ended, err
+		}
+		if ok {
+			ended++
+		}
+	}
+	return ended, nil
+}
+
+// end removes the session when it expires not later than maxExpiresAt and archives it to postgres
+func (s *RedisStore) end(ctx context.Context, id uuid.UUID, family string, maxExpiresAt int64, endedAt time.Time) (bool, error) {
+	keys := []string{keyPrefix + id.String(), expiryKey, familiesKey}
+	fields, err := endScript.Run(ctx, s.client, keys, id.String(), maxExpiresAt, family).Slice()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if fields[0] == nil {
+		// hash истек раньше, чем отработала очистка, архивировать нечего
+		return true, nil
+	}
+	return true, s.archive.CreateEndedSession(ctx, endedSession(id, fields, endedAt))
+}
+
+func (s *RedisStore) PurgeEnded(ctx context.Context, before time.Time) (int64, error) {
+	return purgeEnded(ctx, s.archive, before)
+}
+
+func (s *RedisStore) Stats(ctx context.Context, now time.Time) (Stats, error) {
+	stats, err := endedStats(ctx, s.archive)
+	if err != nil {
+		return stats, err
+	}
+	result, err := statsScript.Run(ctx, s.client, []string{expiryKey, familiesKey}, now.UnixMilli()).Slice()
+	if err != nil {
+		return stats, err
+	}
+	stats.ActiveSessions, _ = result[0].(int64)
+	for i := 1; i+1 < len(result); i += 2 {
+		family, _ := result[i].(string)
+		count, _ := result[i+1].(int64)
+		stats.UserAgentFamilies[family] = count
+	}
+	return stats, nil
+}
+
+// Active orders the sessions by expires_at, it moves forward on every touch
+func (s *RedisStore) Active(ctx context.Context, now time.Time, limit int) ([]db.Session, error) {
+	members, err := s.client.ZRevRangeByScore(ctx, expiryKey, &redis.ZRangeBy{
+		Min:   strconv.FormatInt(now.UnixMilli(), 10),
+		Max:   "+inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]db.Session, 0, len(members))
+	for _, member := range members {
+		_, idStr, _ := strings.Cut(member, ":")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			continue
+		}
+		session, err := s.Get(ctx, id)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		result = append(result, session)
+	}
+	return result, nil
+}
+
+func (s *RedisStore) update(ctx context.Context, id uuid.UUID, expiresAt time.Time, values ...interface{}) error {
+	var expiresAtMs int64
+	if !expiresAt.IsZero() {
+		expiresAtMs = expiresAt.UnixMilli()
+	}
+	args := append([]interface{}{expiresAtMs, endGrace.Milliseconds(), id.String()}, values...)
+	keys := []string{keyPrefix + id.String(), expiryKey}
+	updated, err := updateScript.Run(ctx, s.client, keys, args...).Int()
+	if errors.Is(err, redis.Nil) || (err == nil && updated == 0) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// endedSession builds the archive row from the HMGET fields of endScript
+func endedSession(id uuid.UUID, fields []interface{}, endedAt time.Time) db.CreateEndedSessionParams {
+	field := func(i int) string {
+		value, _ := fields[i].(string)
+		return value
+	}
+	lastActive := timestamp(field(2))
+	createdAt := timestamp(field(4))
+	length := pgtype.Int4{}
+	if lastActive.Valid && createdAt.Valid {
+		length = pgtype.Int4{Int32: int32(max(lastActive.Time.Sub(createdAt.Time), 0).Seconds()), Valid: true}
+	}
+	return db.CreateEndedSessionParams{
+		ID:                   pgtype.UUID{Bytes: id, Valid: true},
+		UserAgent:            field(0),
+		ClientIp:             field(1),
+		LastActive:           lastActive,
+		ExpiresAt:            timestamp(field(3)).Time,
+		SessionLengthSeconds: length,
+		EndedAt:              pgtype.Timestamptz{Time: endedAt, Valid: true},
+		CreatedAt:            createdAt,
+	}
+}
+
+func unixMilli(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func text(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+func timestamp(value string) pgtype.Timestamptz {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: time.UnixMilli(ms), Valid: true}
+}
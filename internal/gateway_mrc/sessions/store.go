@@ -0,0 +1,69 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"time"
+)
+
+// Session store backends, SESSION_STORE
+const (
+	StoreRedis    = "redis"
+	StorePostgres = "postgres"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore keeps the gateway sessions, the tokens of a signed in user are stored only here
+type SessionStore interface {
+	Create(ctx context.Context, args CreateSessionParams) (db.Session, error)
+	// Get returns ErrSessionNotFound for unknown and expired sessions
+	Get(ctx context.Context, id uuid.UUID) (db.Session, error)
+	// UpdateTokens replaces the non-empty tokens
+	UpdateTokens(ctx context.Context, id uuid.UUID, accessToken, refreshToken string) error
+	// UpdateData replaces session_data
+	UpdateData(ctx context.Context, id uuid.UUID, data string) error
+	// Touch records the activity and moves the expiry forward
+	Touch(ctx context.Context, id uuid.UUID, lastActive, expiresAt time.Time) error
+	Block(ctx context.Context, id uuid.UUID) error
+	// End finishes the session on logout or rotation, the tokens are dropped
+	End(ctx context.Context, id uuid.UUID, endedAt time.Time) error
+	// EndExpired ends up to limit sessions past expires_at, fills session_length_seconds
+	// and keeps them in the sessions table for the statistics
+	EndExpired(ctx context.Context, now time.Time, limit int) (int64, error)
+	// PurgeEnded deletes the sessions which ended before the time
+	PurgeEnded(ctx context.Context, before time.Time) (int64, error)
+	Stats(ctx context.Context, now time.Time) (Stats, error)
+	// Active returns up to limit sessions which have not expired, the recently used first
+	Active(ctx context.Context, now time.Time, limit int) ([]db.Session, error)
+}
+
+type Stats struct {
+	ActiveSessions       int64            `json:"active_sessions"`
+	EndedSessions        int64            `json:"ended_sessions"`
+	AverageLengthSeconds float64          `json:"average_length_seconds"`
+	UserAgentFamilies    map[string]int64 `json:"user_agent_families"`
+}
+
+type CreateSessionParams struct {
+	UserAgent    string
+	ClientIp     string
+	ExpiresAt    time.Time
+	AccessToken  string
+	RefreshToken string
+}
+
+// NewSessionStore creates the backend selected by SESSION_STORE, the ended sessions are always kept in postgres
+func NewSessionStore(kind string, redisClient redis.UniversalClient, store db.Store) (SessionStore, error) {
+	switch kind {
+	case StoreRedis, "":
+		return NewRedisStore(redisClient, store), nil
+	case StorePostgres:
+		return NewPostgresStore(store), nil
+	}
+	return nil, fmt.Errorf("unknown session store %q", kind)
+}
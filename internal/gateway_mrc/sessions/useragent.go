@@ -0,0 +1,31 @@
+package sessions
+
+import "strings"
+
+// UserAgentFamily groups user agents for the session statistics, the order of the checks matters:
+// Edge and Opera also send Chrome, Chrome also sends Safari.
+func UserAgentFamily(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return "unknown"
+	case strings.Contains(ua, "bot"), strings.Contains(ua, "crawler"), strings.Contains(ua, "spider"):
+		return "bot"
+	case strings.Contains(ua, "edg/"), strings.Contains(ua, "edga/"), strings.Contains(ua, "edgios/"):
+		return "edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		return "opera"
+	case strings.Contains(ua, "yabrowser/"):
+		return "yandex"
+	case strings.Contains(ua, "firefox/"), strings.Contains(ua, "fxios/"):
+		return "firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"), strings.Contains(ua, "chromium/"):
+		return "chrome"
+	case strings.Contains(ua, "safari/"):
+		return "safari"
+	case strings.HasPrefix(ua, "curl/"), strings.HasPrefix(ua, "wget/"), strings.HasPrefix(ua, "postmanruntime/"),
+		strings.HasPrefix(ua, "go-http-client/"), strings.HasPrefix(ua, "python-requests/"):
+		return "tool"
+	}
+	return "other"
+}
@@ -0,0 +1,248 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+	"io"
+	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"job_search_platform/pkg/config"
+	"job_search_platform/pkg/entities/common"
+	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/metrics"
+	"net/http"
+	"time"
+)
+
+const (
+	refreshLockPrefix = "session_refresh:"
+	// refreshLockPoll is how often an instance waiting for the lock re-reads the session
+	refreshLockPoll        = 50 * time.Millisecond
+	maxRefreshResponseSize = 64 << 10
+	tokenRefreshService    = "users_mrc"
+	tokenRefreshPath       = "/api/v1/auth/public/refresh-token"
+)
+
+var ErrRefreshInProgress = errors.New("token refresh is in progress on another gateway instance")
+
+// releaseLock deletes the lock only when it is still held by the caller
+var releaseLock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Upstream sends a request of the gateway to an instance of the service, proxy.Forwarder implements it
+type Upstream interface {
+	Do(ctx context.Context, service string, newRequest func(target string) (*http.Request, error)) (*http.Response, error)
+}
+
+// TokenRefresher refreshes the access token of a session once for all its concurrent requests:
+// requests of one instance share a single call, instances are serialized by a redis lock.
+type TokenRefresher struct {
+	sessions   *SessionsUsecase
+	redis      redis.UniversalClient
+	tokenMaker jwt_token.Maker
+	upstream   Upstream
+	timeout    time.Duration
+	// refreshBefore is how long before the expiry the access token is refreshed proactively
+	refreshBefore time.Duration
+	lockTtl       time.Duration
+	group         singleflight.Group
+}
+
+func NewTokenRefresher(
+	sessions *SessionsUsecase,
+	redisClient redis.UniversalClient,
+	tokenMaker jwt_token.Maker,
+	upstream Upstream,
+	config config.GatewayMrc,
+) *TokenRefresher {
+	return &TokenRefresher{
+		sessions:      sessions,
+		redis:         redisClient,
+		tokenMaker:    tokenMaker,
+		upstream:      upstream,
+		timeout:       config.TokenRefreshTimeout,
+		refreshBefore: config.TokenRefreshBefore,
+		// лок переживает запрос к users_mrc даже при таймауте клиента
+		lockTtl: 2 * config.TokenRefreshTimeout,
+	}
+}
+
+// NeedsRefresh reports whether the access token should be refreshed before it is used
+func (r *TokenRefresher) NeedsRefresh(payload *jwt_token.Payload) bool {
+	return time.Until(payload.ExpiredAt) < r.refreshBefore
+}
+
+// Refresh returns the session with a fresh access token and its payload.
+// The session passed in is the one the caller has seen, a newer token already stored by
+// another request or instance is reused instead of calling users_mrc again.
+func (r *TokenRefresher) Refresh(ctx context.Context, session db.Session) (db.Session, *jwt_token.Payload, int32, error) {
+	id := uuid.UUID(session.ID.Bytes)
+	result, err, _ := r.group.Do(id.String(), func() (interface{}, error) {
+		// запрос, начавший обновление, может завершиться раньше остальных
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.lockTtl)
+		defer cancel()
+		return r.refresh(ctx, id, session.AccessToken.String)
+	})
+	if err != nil {
+		metrics.TokenRefreshes.WithLabelValues("failed").Inc()
+		var refreshErr *refreshError
+		if errors.As(err, &refreshErr) {
+			return session, nil, refreshErr.code, refreshErr.err
+		}
+		return session, nil, server.SENDING_TOKEN_REFRESH_ERR_CODE, err
+	}
+	refreshed := result.(refreshResult)
+	if refreshed.reused {
+		metrics.TokenRefreshes.WithLabelValues("reused").Inc()
+	} else {
+		metrics.TokenRefreshes.WithLabelValues("refreshed").Inc()
+	}
+	return refreshed.session, refreshed.payload, server.SUCCESS_CODE, nil
+}
+
+type refreshResult struct {
+	session db.Session
+	payload *jwt_token.Payload
+	// reused is set when another request or instance has already refreshed the token
+	reused bool
+}
+
+type refreshError struct {
+	code int32
+	err  error
+}
+
+func (e *refreshError) Error() string {
+	return e.err.Error()
+}
+
+func (e *refreshError) Unwrap() error {
+	return e.err
+}
+
+func (r *TokenRefresher) refresh(ctx context.Context, id uuid.UUID, staleToken string) (refreshResult, error) {
+	lockKey := refreshLockPrefix + id.String()
+	owner, err := lockToken()
+	if err != nil {
+		return refreshResult{}, err
+	}
+	for {
+		// токен мог обновить другой инстанс, пока этот ждал лок
+		current, err := r.current(ctx, id, staleToken)
+		if err != nil || current.payload != nil {
+			return current, err
+		}
+		acquired, err := r.redis.SetNX(ctx, lockKey, owner, r.lockTtl).Result()
+		if err != nil {
+			return refreshResult{}, err
+		}
+		if acquired {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return refreshResult{}, ErrRefreshInProgress
+		case <-time.After(refreshLockPoll):
+		}
+	}
+	defer releaseLock.Run(context.WithoutCancel(ctx), r.redis, []string{lockKey}, owner)
+
+	current, err := r.current(ctx, id, staleToken)
+	if err != nil || current.payload != nil {
+		return current, err
+	}
+	session := current.session
+	accessToken, err := r.requestAccessToken(ctx, session.RefreshToken.String)
+	if err != nil {
+		return refreshResult{}, err
+	}
+	payload, err := r.tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return refreshResult{}, &refreshError{code: r.tokenMaker.GetErrorCode(err), err: err}
+	}
+	sessionId, _ := session.ID.Value()
+	errCode, err := r.sessions.UpdateSession(ctx, sessionId.(string), "", accessToken)
+	if err != nil {
+		return refreshResult{}, &refreshError{code: errCode, err: err}
+	}
+	session.AccessToken = pgtype.Text{String: accessToken, Valid: true}
+	return refreshResult{session: session, payload: payload}, nil
+}
+
+// current reads the stored session, the payload is set when its access token differs
+// from the stale one and does not need a refresh
+func (r *TokenRefresher) current(ctx context.Context, id uuid.UUID, staleToken string) (refreshResult, error) {
+	session, errCode, err := r.sessions.GetSession(ctx, id.String())
+	if err != nil {
+		return refreshResult{}, &refreshError{code: errCode, err: err}
+	}
+	if session.AccessToken.String == staleToken {
+		return refreshResult{session: session}, nil
+	}
+	payload, err := r.tokenMaker.VerifyToken(session.AccessToken.String)
+	if err != nil || r.NeedsRefresh(payload) {
+		return refreshResult{session: session}, nil
+	}
+	return refreshResult{session: session, payload: payload, reused: true}, nil
+}
+
+func (r *TokenRefresher) requestAccessToken(ctx context.Context, refreshToken string) (string, error) {
+	var respData common.RefreshTokenResponse
+	body, err := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	resp, err := r.upstream.Do(ctx, tokenRefreshService, func(target string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, target+tokenRefreshPath, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(io.LimitReader(resp.Body, maxRefreshResponseSize))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to refresh token, status code: %d", resp.StatusCode)
+		// отозванный или просроченный refresh токен обновлять бесполезно
+		if resp.StatusCode < http.StatusInternalServerError && json.Unmarshal(body, &respData) == nil && respData.Code != 0 {
+			return "", &refreshError{code: int32(respData.Code), err: err}
+		}
+		return "", err
+	}
+	err = json.Unmarshal(body, &respData)
+	if err != nil {
+		return "", err
+	}
+	return respData.AccessToken, nil
+}
+
+func lockToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
@@ -2,61 +2,182 @@ package usecases
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	db "job_search_platform/internal/gateway_mrc/db/sqlc"
+	"job_search_platform/internal/gateway_mrc/sessions"
+	"job_search_platform/pkg/config"
 	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/metrics"
 	"time"
 )
 
+// cleanupBatchSize limits a single EndExpired call, the job repeats it until nothing is left
+const cleanupBatchSize = 500
+
 type SessionsUsecase struct {
-	store db.Store
+	store         sessions.SessionStore
+	duration      time.Duration
+	touchInterval time.Duration
+	retention     time.Duration
 }
 
-func NewSessionsUsecase(store db.Store) SessionsUsecase {
-	return SessionsUsecase{store}
+func NewSessionsUsecase(store sessions.SessionStore, config config.GatewayMrc) SessionsUsecase {
+	return SessionsUsecase{
+		store:         store,
+		duration:      time.Duration(config.SessionDuration) * 24 * time.Hour,
+		touchInterval: config.SessionTouchInterval,
+		retention:     config.SessionRetention,
+	}
 }
 
-func (uc *SessionsUsecase) UpdateSession(ctx context.Context, sessionIdStr string, refreshToken, accessToken string) (db.Session, int32, error) {
-	var session db.Session
-	var err error
-	sessionId, err := uuid.Parse(sessionIdStr)
+func (uc *SessionsUsecase) CreateSession(ctx context.Context, clientIp, userAgent string) (db.Session, int32, error) {
+	session, err := uc.store.Create(ctx, sessions.CreateSessionParams{
+		UserAgent: userAgent,
+		ClientIp:  clientIp,
+		ExpiresAt: time.Now().Add(uc.duration),
+	})
 	if err != nil {
-		return session, server.SESSION_PARSING_ERR_CODE, err
-	}
-	sessionArgs := &db.UpdateSessionDataParams{
-		AccessToken:  pgtype.Text{String: accessToken, Valid: accessToken != ""},
-		RefreshToken: pgtype.Text{String: refreshToken, Valid: refreshToken != ""},
-		ID:           pgtype.UUID{Bytes: sessionId, Valid: true},
+		return session, sessionErrorCode(err), err
 	}
-	session, err = uc.store.UpdateSessionData(ctx, *sessionArgs)
+	metrics.SessionsCreated.Inc()
+	return session, server.SUCCESS_CODE, nil
+}
+
+// RotateSession starts a new session with the token pair and ends the previous one, so an identifier
+// planted before sign-in is useless afterwards (session fixation)
+func (uc *SessionsUsecase) RotateSession(
+	ctx context.Context, previous db.Session, clientIp, userAgent, accessToken, refreshToken string,
+) (db.Session, int32, error) {
+	session, err := uc.store.Create(ctx, sessions.CreateSessionParams{
+		UserAgent:    userAgent,
+		ClientIp:     clientIp,
+		ExpiresAt:    time.Now().Add(uc.duration),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
 	if err != nil {
-		return session, db.ErrorCode(err), err
+		return session, sessionErrorCode(err), err
+	}
+	metrics.SessionsCreated.Inc()
+	if previous.ID.Valid {
+		if errCode, err := uc.endSession(ctx, previous.ID.Bytes, "rotated"); err != nil {
+			return session, errCode, err
+		}
 	}
 	return session, server.SUCCESS_CODE, nil
 }
 
-func (uc *SessionsUsecase) UpdateSessionLastActive(ctx context.Context, sessionId uuid.UUID) (db.Session, int32, error) {
-	sessionArgs := &db.UpdateSessionDataParams{
-		LastActive: pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		ID:         pgtype.UUID{Bytes: sessionId, Valid: true},
+func (uc *SessionsUsecase) EndSession(ctx context.Context, sessionId uuid.UUID) (int32, error) {
+	return uc.endSession(ctx, sessionId, "logout")
+}
+
+func (uc *SessionsUsecase) endSession(ctx context.Context, sessionId uuid.UUID, reason string) (int32, error) {
+	err := uc.store.End(ctx, sessionId, time.Now())
+	if err != nil {
+		return sessionErrorCode(err), err
+	}
+	metrics.SessionsEnded.WithLabelValues(reason).Inc()
+	return server.SUCCESS_CODE, nil
+}
+
+func (uc *SessionsUsecase) UpdateSession(ctx context.Context, sessionIdStr string, refreshToken, accessToken string) (int32, error) {
+	sessionId, err := uuid.Parse(sessionIdStr)
+	if err != nil {
+		return server.SESSION_PARSING_ERR_CODE, err
 	}
-	session, err := uc.store.UpdateSessionData(ctx, *sessionArgs)
+	err = uc.store.UpdateTokens(ctx, sessionId, accessToken, refreshToken)
 	if err != nil {
-		return session, db.ErrorCode(err), err
+		return sessionErrorCode(err), err
 	}
-	return session, server.SUCCESS_CODE, nil
+	return server.SUCCESS_CODE, nil
+}
+
+// Touch продлевает сессию, last_active пишется не чаще SESSION_TOUCH_INTERVAL.
+// Returns true when the session was written.
+func (uc *SessionsUsecase) Touch(ctx context.Context, session *db.Session) (bool, int32, error) {
+	now := time.Now()
+	if session.LastActive.Valid && now.Sub(session.LastActive.Time) < uc.touchInterval {
+		return false, server.SUCCESS_CODE, nil
+	}
+	expiresAt := now.Add(uc.duration)
+	err := uc.store.Touch(ctx, session.ID.Bytes, now, expiresAt)
+	if err != nil {
+		return false, sessionErrorCode(err), err
+	}
+	session.LastActive = pgtype.Timestamptz{Time: now, Valid: true}
+	session.ExpiresAt = expiresAt
+	return true, server.SUCCESS_CODE, nil
 }
 
 func (uc *SessionsUsecase) GetSession(ctx context.Context, id string) (db.Session, int32, error) {
 	var session db.Session
 	sessionId, err := uuid.Parse(id)
 	if err != nil {
-		return session, db.ErrorCode(err), err
+		return session, server.SESSION_PARSING_ERR_CODE, err
 	}
-	session, err = uc.store.GetSession(ctx, pgtype.UUID{Bytes: sessionId, Valid: true})
+	session, err = uc.store.Get(ctx, sessionId)
 	if err != nil {
-		return session, db.ErrorCode(err), err
+		return session, sessionErrorCode(err), err
+	}
+	// истекшую сессию очистка могла еще не завершить
+	if session.ExpiresAt.Before(time.Now()) {
+		return session, server.SESSION_EXPIRED_ERR_CODE, errors.New("session has expired")
+	}
+	return session, server.SUCCESS_CODE, nil
+}
+
+// CleanupSessions ends the expired sessions and deletes the ended ones older than SESSION_RETENTION
+func (uc *SessionsUsecase) CleanupSessions(ctx context.Context) (ended int64, purged int64, err error) {
+	now := time.Now()
+	for {
+		count, err := uc.store.EndExpired(ctx, now, cleanupBatchSize)
+		ended += count
+		metrics.SessionsEnded.WithLabelValues("expired").Add(float64(count))
+		if err != nil {
+			return ended, purged, err
+		}
+		if count < cleanupBatchSize {
+			break
+		}
+	}
+	purged, err = uc.store.PurgeEnded(ctx, now.Add(-uc.retention))
+	return ended, purged, err
+}
+
+func (uc *SessionsUsecase) GetStats(ctx context.Context) (sessions.Stats, int32, error) {
+	stats, err := uc.store.Stats(ctx, time.Now())
+	if err != nil {
+		return stats, sessionErrorCode(err), err
+	}
+	return stats, server.SUCCESS_CODE, nil
+}
+
+// CsrfToken returns the synchronizer token of the session and issues it on the first call
+func (uc *SessionsUsecase) CsrfToken(ctx context.Context, session *db.Session) (string, int32, error) {
+	data := sessions.ParseData(session.SessionData.String)
+	if data.CsrfToken != "" {
+		return data.CsrfToken, server.SUCCESS_CODE, nil
+	}
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", server.UNKNOWN_ERROR_CODE, err
+	}
+	data.CsrfToken = base64.RawURLEncoding.EncodeToString(token)
+	encoded := data.Encode()
+	if err := uc.store.UpdateData(ctx, session.ID.Bytes, encoded); err != nil {
+		return "", sessionErrorCode(err), err
+	}
+	session.SessionData = pgtype.Text{String: encoded, Valid: true}
+	return data.CsrfToken, server.SUCCESS_CODE, nil
+}
+
+func sessionErrorCode(err error) int32 {
+	if errors.Is(err, sessions.ErrSessionNotFound) {
+		return server.SESSION_NOT_FOUND_ERR_CODE
 	}
-	return session, db.ErrorCode(err), nil
+	return db.ErrorCode(err)
 }
@@ -0,0 +1,23 @@
+-- name: CreateLoginToken :one
+INSERT INTO login_tokens (
+    user_id,
+    token_hash,
+    session_id,
+    expires_at
+) VALUES ($1, $2, $3, $4)
+    RETURNING *;
+
+-- name: GetActiveLoginToken :one
+SELECT * FROM login_tokens
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW();
+
+-- name: UseLoginToken :one
+UPDATE login_tokens
+SET
+    used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+    RETURNING *;
+
+-- name: DeleteExpiredLoginTokens :exec
+DELETE FROM login_tokens
+WHERE expires_at < NOW();
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- name: GetNotificationPreferencesByUserId :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1
+ORDER BY channel, category;
+
+-- name: GetNotificationPreferenceByEmail :one
+SELECT np.enabled
+FROM notification_preferences np
+         JOIN users u ON u.id = np.user_id
+WHERE u.email = $1 AND np.channel = $2 AND np.category = $3;
+
+-- name: UpsertNotificationPreference :one
+INSERT INTO notification_preferences (
+    user_id,
+    channel,
+    category,
+    enabled,
+    updated_at
+) VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (user_id, channel, category) DO UPDATE
+SET
+    enabled = EXCLUDED.enabled,
+    updated_at = NOW()
+    RETURNING *;
\ No newline at end of file
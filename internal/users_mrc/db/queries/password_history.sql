@@ -0,0 +1,22 @@
+-- name: CreatePasswordHistory :one
+INSERT INTO password_history (
+    user_id,
+    password
+) VALUES ($1, $2)
+    RETURNING *;
+
+-- name: GetPasswordHistoryByUserId :many
+SELECT * FROM password_history
+WHERE user_id = $1
+ORDER BY created_at DESC
+    LIMIT $2;
+
+-- name: DeleteOldPasswordHistory :exec
+DELETE FROM password_history
+WHERE user_id = $1
+  AND id NOT IN (
+    SELECT ph.id FROM password_history ph
+    WHERE ph.user_id = $1
+    ORDER BY ph.created_at DESC
+        LIMIT $2
+);
\ No newline at end of file
@@ -36,4 +36,14 @@ SELECT
     g.id AS group_id
 FROM user_groups ug
          JOIN groups g ON g.id = ug.group_id
-WHERE ug.user_id = $1;
\ No newline at end of file
+WHERE ug.user_id = $1;
+
+-- name: UpsertGroup :one
+INSERT INTO groups(name) VALUES ($1)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+    RETURNING *;
+
+-- name: UpsertPermission :one
+INSERT INTO permissions(name, codename) VALUES ($1, $2)
+ON CONFLICT (codename) DO UPDATE SET name = EXCLUDED.name
+    RETURNING *;
@@ -0,0 +1,24 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (
+    token_id,
+    user_id,
+    expires_at
+) VALUES ($1, $2, $3)
+ON CONFLICT (token_id) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE token_id = $1);
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expires_at < NOW();
+
+-- name: RevokeUserTokens :exec
+INSERT INTO user_token_revocations (
+    user_id,
+    revoked_before
+) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before;
+
+-- name: AreUserTokensRevoked :one
+SELECT EXISTS(SELECT 1 FROM user_token_revocations WHERE user_id = $1 AND revoked_before > $2);
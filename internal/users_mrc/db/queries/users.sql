@@ -7,9 +7,10 @@ INSERT INTO users (
     first_name,
     auth_source,
     sexy,
+    lang_code,
     last_token_update
 ) VALUES
-    ($1, $2, $3, $4, $5, $6, $7, NOW())
+    ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
     RETURNING *;
 
 -- name: LastTokenUpdate :exec
@@ -24,6 +25,7 @@ SET
     first_name = COALESCE(sqlc.narg('first_name'), first_name),
     last_name = COALESCE(sqlc.narg('last_name'), last_name),
     verified_email = COALESCE(sqlc.narg('verified_email'), verified_email),
+    lang_code = COALESCE(sqlc.narg('lang_code'), lang_code),
     updated_at = NOW()
 WHERE id = sqlc.arg('id')
     RETURNING *;
@@ -57,6 +59,7 @@ SELECT
     u.date_joined,
     u.is_deleted,
     u.user_type,
+    u.lang_code,
     COALESCE(ARRAY_AGG(g.name), '{}') AS groups
 FROM users u
          LEFT JOIN user_groups ug ON u.id = ug.user_id
@@ -90,6 +93,13 @@ SET
     is_deleted = $2
 WHERE email = $1;
 
+-- name: BanUserByEmail :one
+UPDATE users
+SET
+    is_banned = $2
+WHERE email = $1
+    RETURNING *;
+
 -- name: ChangePassword :exec
 UPDATE users
 SET
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS phones;
+DROP TABLE IF EXISTS users;
+DROP TYPE IF EXISTS sexy;
+DROP TYPE IF EXISTS user_types;
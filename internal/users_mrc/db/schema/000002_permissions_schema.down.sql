@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_permissions;
+DROP TABLE IF EXISTS group_permissions;
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS permissions;
@@ -35,49 +35,3 @@ CREATE TABLE user_permissions (
     created_at TIMESTAMPTZ DEFAULT NOW(),
     UNIQUE (user_id, permission_id)
 );
-
-INSERT INTO permissions(name, codename)
-VALUES
-    ('Add Post', 'add_post'),
-    ('Change Post', 'change_post'),
-    ('Delete Post', 'delete_post'),
-    ('Hide Post', 'hide_post'),
-    ('Block Post', 'block_post'),
-    ('View Post', 'view_post');
-
-INSERT INTO groups (name)
-VALUES
-    ('administrators'),
-    ('moderators'),
-    ('ordinary_users'),
-    ('contributors'),
-    ('product_managers'),
-    ('project_managers'),
-    ('premium_users'),
-    ('testers '),
-    ('supports'),
-    ('developers'),
-    ('companies'),
-    ('job_seekers');
-
--- INSERT INTO group_permissions (group_id, permission_id)
--- SELECT
---     (SELECT id FROM groups WHERE name = 'administrators'),
---     id
--- FROM permissions
--- WHERE codename IN ('add_post', 'edit_post', 'delete_post', 'view_post', 'block_post');
---
--- INSERT INTO group_permissions (group_id, permission_id)
--- SELECT
---     (SELECT id FROM groups WHERE name = 'moderators'),
---     id
--- FROM permissions
--- WHERE codename IN ('delete_post', 'view_post', 'block_post', 'hide_post');
---
---
--- INSERT INTO group_permissions (group_id, permission_id)
--- SELECT
---     (SELECT id FROM groups WHERE name = 'ordinary_users'),
---     id
--- FROM permissions
--- WHERE codename IN ('add_post', 'edit_post', 'delete_post', 'hide_post', 'view_post');
\ No newline at end of file
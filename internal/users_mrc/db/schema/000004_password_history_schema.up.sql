@@ -0,0 +1,9 @@
+CREATE TABLE password_history (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX password_history_user_id_idx ON password_history (user_id, created_at DESC);
@@ -0,0 +1,10 @@
+CREATE TABLE login_tokens (
+    id UUID PRIMARY KEY NOT NULL DEFAULT (uuid_generate_v4()),
+    user_id UUID NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,     -- SHA-256 от токена, сам токен не хранится
+    session_id VARCHAR(64),                     -- сессия gateway, из которой запрошена ссылка
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_preferences;
+DROP TYPE IF EXISTS notification_categories;
+DROP TYPE IF EXISTS notification_channels;
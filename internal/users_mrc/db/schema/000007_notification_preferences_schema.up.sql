@@ -0,0 +1,14 @@
+CREATE TYPE notification_channels AS ENUM('email', 'sms', 'in_app');
+CREATE TYPE notification_categories AS ENUM('security', 'job_alerts', 'marketing', 'messages');
+
+-- Отсутствие строки означает значение по умолчанию (см. pkg/notifications)
+CREATE TABLE notification_preferences (
+    id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    channel notification_channels NOT NULL,
+    category notification_categories NOT NULL,
+    enabled BOOL NOT NULL DEFAULT true,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, channel, category)
+);
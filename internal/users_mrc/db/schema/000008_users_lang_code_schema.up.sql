@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN lang_code VARCHAR(5) NOT NULL DEFAULT 'ru';  -- Язык писем и сообщений об ошибках
@@ -0,0 +1,9 @@
+CREATE TABLE revoked_tokens (
+    token_id UUID PRIMARY KEY NOT NULL,         -- id из payload refresh токена
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,            -- после истечения токена запись не нужна
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE group_permissions DROP CONSTRAINT IF EXISTS group_permissions_group_id_permission_id_key;
+ALTER TABLE permissions DROP CONSTRAINT IF EXISTS permissions_codename_key;
+ALTER TABLE groups DROP CONSTRAINT IF EXISTS groups_name_key;
@@ -0,0 +1,6 @@
+-- группы и права создает `platformctl seed`, повторный запуск не должен добавлять дубли
+UPDATE groups SET name = TRIM(name);
+
+ALTER TABLE groups ADD CONSTRAINT groups_name_key UNIQUE (name);
+ALTER TABLE permissions ADD CONSTRAINT permissions_codename_key UNIQUE (codename);
+ALTER TABLE group_permissions ADD CONSTRAINT group_permissions_group_id_permission_id_key UNIQUE (group_id, permission_id);
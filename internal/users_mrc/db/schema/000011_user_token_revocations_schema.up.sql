@@ -0,0 +1,6 @@
+-- refresh токены пользователя, выданные до revoked_before, недействительны (сброс пароля администратором)
+CREATE TABLE user_token_revocations (
+    user_id UUID PRIMARY KEY NOT NULL,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
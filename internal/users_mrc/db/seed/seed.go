@@ -0,0 +1,55 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "job_search_platform/internal/users_mrc/db/sqlc"
+)
+
+const (
+	AdministratorsGroup = "administrators"
+	OrdinaryUsersGroup  = "ordinary_users"
+)
+
+// Permissions and Groups were inserted by 000002_permissions_schema before, the codename is the key
+var Permissions = []db.UpsertPermissionParams{
+	permission("Add Post", "add_post"),
+	permission("Change Post", "change_post"),
+	permission("Delete Post", "delete_post"),
+	permission("Hide Post", "hide_post"),
+	permission("Block Post", "block_post"),
+	permission("View Post", "view_post"),
+}
+
+var Groups = []string{
+	AdministratorsGroup,
+	"moderators",
+	OrdinaryUsersGroup,
+	"contributors",
+	"product_managers",
+	"project_managers",
+	"premium_users",
+	"testers",
+	"supports",
+	"developers",
+	"companies",
+	"job_seekers",
+}
+
+// Run creates the groups and permissions without granting any of them to the groups, users_mrc runs it on start after the migrations
+// and `platformctl seed` on demand
+func Run(ctx context.Context, store db.Store) error {
+	err := store.TxSeed(ctx, &db.SeedTxParams{
+		Permissions: Permissions,
+		Groups:      Groups,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot seed groups and permissions: %w", err)
+	}
+	return nil
+}
+
+func permission(name, codename string) db.UpsertPermissionParams {
+	return db.UpsertPermissionParams{Name: name, Codename: pgtype.Text{String: codename, Valid: true}}
+}
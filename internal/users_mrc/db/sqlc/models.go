@@ -7,10 +7,98 @@ package db
 import (
 	"database/sql/driver"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type NotificationCategories string
+
+const (
+	NotificationCategoriesSecurity  NotificationCategories = "security"
+	NotificationCategoriesJobAlerts NotificationCategories = "job_alerts"
+	NotificationCategoriesMarketing NotificationCategories = "marketing"
+	NotificationCategoriesMessages  NotificationCategories = "messages"
+)
+
+func (e *NotificationCategories) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationCategories(s)
+	case string:
+		*e = NotificationCategories(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationCategories: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationCategories struct {
+	NotificationCategories NotificationCategories `json:"notification_categories"`
+	Valid                  bool                   `json:"valid"` // Valid is true if NotificationCategories is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationCategories) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationCategories, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationCategories.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationCategories) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationCategories), nil
+}
+
+type NotificationChannels string
+
+const (
+	NotificationChannelsEmail NotificationChannels = "email"
+	NotificationChannelsSms   NotificationChannels = "sms"
+	NotificationChannelsInApp NotificationChannels = "in_app"
+)
+
+func (e *NotificationChannels) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationChannels(s)
+	case string:
+		*e = NotificationChannels(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationChannels: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationChannels struct {
+	NotificationChannels NotificationChannels `json:"notification_channels"`
+	Valid                bool                 `json:"valid"` // Valid is true if NotificationChannels is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationChannels) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationChannels, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationChannels.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationChannels) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationChannels), nil
+}
+
 type Sexy string
 
 const (
@@ -118,6 +206,32 @@ type Invite struct {
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
+type LoginToken struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	SessionID pgtype.Text        `json:"session_id"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type NotificationPreference struct {
+	ID        int32                  `json:"id"`
+	UserID    pgtype.UUID            `json:"user_id"`
+	Channel   NotificationChannels   `json:"channel"`
+	Category  NotificationCategories `json:"category"`
+	Enabled   bool                   `json:"enabled"`
+	UpdatedAt pgtype.Timestamptz     `json:"updated_at"`
+}
+
+type PasswordHistory struct {
+	ID        int32              `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Password  string             `json:"password"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Permission struct {
 	ID       int32       `json:"id"`
 	Name     string      `json:"name"`
@@ -134,6 +248,13 @@ type Phone struct {
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 }
 
+type RevokedToken struct {
+	TokenID   pgtype.UUID        `json:"token_id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type User struct {
 	ID              pgtype.UUID        `json:"id"`
 	Email           string             `json:"email"`
@@ -149,6 +270,7 @@ type User struct {
 	IsBanned        pgtype.Bool        `json:"is_banned"`
 	DateJoined      pgtype.Timestamptz `json:"date_joined"`
 	Sexy            NullSexy           `json:"sexy"`
+	LangCode        string             `json:"lang_code"`
 }
 
 type UserGroup struct {
@@ -164,3 +286,8 @@ type UserPermission struct {
 	PermissionID int32              `json:"permission_id"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
+
+type UserTokenRevocation struct {
+	UserID        pgtype.UUID `json:"user_id"`
+	RevokedBefore time.Time   `json:"revoked_before"`
+}
@@ -163,3 +163,34 @@ func (q *Queries) UpdateGroup(ctx context.Context, arg UpdateGroupParams) (Group
 	err := row.Scan(&i.ID, &i.Name)
 	return i, err
 }
+
+const upsertGroup = `-- name: UpsertGroup :one
+INSERT INTO groups(name) VALUES ($1)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+    RETURNING id, name
+`
+
+func (q *Queries) UpsertGroup(ctx context.Context, name string) (Group, error) {
+	row := q.db.QueryRow(ctx, upsertGroup, name)
+	var i Group
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const upsertPermission = `-- name: UpsertPermission :one
+INSERT INTO permissions(name, codename) VALUES ($1, $2)
+ON CONFLICT (codename) DO UPDATE SET name = EXCLUDED.name
+    RETURNING id, name, codename
+`
+
+type UpsertPermissionParams struct {
+	Name     string      `json:"name"`
+	Codename pgtype.Text `json:"codename"`
+}
+
+func (q *Queries) UpsertPermission(ctx context.Context, arg UpsertPermissionParams) (Permission, error) {
+	row := q.db.QueryRow(ctx, upsertPermission, arg.Name, arg.Codename)
+	var i Permission
+	err := row.Scan(&i.ID, &i.Name, &i.Codename)
+	return i, err
+}
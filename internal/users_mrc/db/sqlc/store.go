@@ -7,7 +7,11 @@ import (
 
 type Store interface {
 	Querier
-	TxCreateUser(ctx context.Context, args *CreateOrdinaryUserTxParams) error
+	TxCreateUser(ctx context.Context, args *CreateOrdinaryUserTxParams, hashPass string) error
+	TxCreateStaffUser(ctx context.Context, args *CreateStaffUserTxParams, hashPass string, groupNames ...string) (User, error)
+	TxChangePassword(ctx context.Context, args *ChangePasswordTxParams) error
+	TxSeed(ctx context.Context, args *SeedTxParams) error
+	TxUpdateNotificationPreferences(ctx context.Context, args []UpsertNotificationPreferenceParams) error
 }
 
 type SQLStore struct {
@@ -2,13 +2,14 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"github.com/jackc/pgx/v5/pgtype"
-	"job_search_platform/pkg/helpers/crypto"
+	"time"
 )
 
 type UserPhone struct {
-	Number      int64  `json:"number"`
-	CountryCode string `json:"country_code"`
+	Number      int64  `json:"number" validate:"required,phone"`
+	CountryCode string `json:"country_code" validate:"required,country_code"`
 }
 
 type BaseUserInfo struct {
@@ -18,8 +19,9 @@ type BaseUserInfo struct {
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
 	Source    string `json:"source,omitempty"`
-	UserType  string `json:"user_type" validate:"required"` // 'company', 'job_seeker'
+	UserType  string `json:"user_type" validate:"required,user_type"` // 'company', 'job_seeker'
 	UserSexy  string `json:"user_sexy"`
+	LangCode  string `json:"lang_code" validate:"omitempty,oneof=ru en kk"`
 }
 
 //Google Auth → google_auth
@@ -37,12 +39,12 @@ type CreateStaffUserTxParams struct {
 	InviteCode string `json:"invite,omitempty"`
 }
 
-func (store *SQLStore) TxCreateUser(ctx context.Context, args *CreateOrdinaryUserTxParams) error {
+// TxCreateUser creates the user with an already hashed password, hashing is done by the caller
+func (store *SQLStore) TxCreateUser(ctx context.Context, args *CreateOrdinaryUserTxParams, hashPass string) error {
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
 		userType := UserTypes(args.UserType)
 		userSexy := Sexy(args.UserSexy)
-		hashPass := crypto.HashPassword(args.Password1)
 		userArgs := &CreateUserParams{
 			Email:      args.Email,
 			FirstName:  pgtype.Text{String: args.FirstName, Valid: args.FirstName != ""},
@@ -51,6 +53,7 @@ func (store *SQLStore) TxCreateUser(ctx context.Context, args *CreateOrdinaryUse
 			AuthSource: args.Source,
 			UserType:   NullUserTypes{UserTypes: userType, Valid: true},
 			Sexy:       NullSexy{Sexy: userSexy, Valid: userSexy != ""},
+			LangCode:   args.LangCode,
 		}
 		group, err := store.GetGroupByName(ctx, "ordinary_users")
 		if err != nil {
@@ -80,6 +83,15 @@ func (store *SQLStore) TxCreateUser(ctx context.Context, args *CreateOrdinaryUse
 		if err != nil {
 			return err
 		}
+
+		historyArgs := &CreatePasswordHistoryParams{
+			UserID:   user.ID,
+			Password: hashPass,
+		}
+		_, err = q.CreatePasswordHistory(ctx, *historyArgs)
+		if err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
@@ -87,3 +99,102 @@ func (store *SQLStore) TxCreateUser(ctx context.Context, args *CreateOrdinaryUse
 	}
 	return nil
 }
+
+// TxCreateStaffUser creates a user without a phone in the groups, the email is considered verified.
+// The invite code is not checked here.
+func (store *SQLStore) TxCreateStaffUser(
+	ctx context.Context, args *CreateStaffUserTxParams, hashPass string, groupNames ...string) (User, error) {
+	var user User
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		userType := UserTypes(args.UserType)
+		userArgs := &CreateUserParams{
+			Email:      args.Email,
+			FirstName:  pgtype.Text{String: args.FirstName, Valid: args.FirstName != ""},
+			LastName:   pgtype.Text{String: args.LastName, Valid: args.LastName != ""},
+			Password:   hashPass,
+			AuthSource: args.Source,
+			UserType:   NullUserTypes{UserTypes: userType, Valid: userType != ""},
+			LangCode:   args.LangCode,
+		}
+		user, err = q.CreateUser(ctx, *userArgs)
+		if err != nil {
+			return err
+		}
+		user, err = q.UpdateUserByEmail(ctx, UpdateUserByEmailParams{
+			VerifiedEmail: pgtype.Bool{Bool: true, Valid: true},
+			Email:         user.Email,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, groupName := range groupNames {
+			group, err := q.GetGroupByName(ctx, groupName)
+			if err != nil {
+				return fmt.Errorf("group %s: %w", groupName, err)
+			}
+			groupArgs := &CreateUserGroupParams{
+				GroupID: group.ID,
+				UserID:  user.ID,
+			}
+			_, err = q.CreateUserGroup(ctx, *groupArgs)
+			if err != nil {
+				return err
+			}
+		}
+
+		historyArgs := &CreatePasswordHistoryParams{
+			UserID:   user.ID,
+			Password: hashPass,
+		}
+		_, err = q.CreatePasswordHistory(ctx, *historyArgs)
+		return err
+	})
+	return user, err
+}
+
+type ChangePasswordTxParams struct {
+	UserID      pgtype.UUID
+	Password    string
+	HistorySize int32
+	// RevokeTokensBefore invalidates the refresh tokens issued before it, zero keeps them
+	RevokeTokensBefore time.Time
+}
+
+// TxChangePassword stores the new password hash and keeps only the last HistorySize entries in password_history
+func (store *SQLStore) TxChangePassword(ctx context.Context, args *ChangePasswordTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		changePassArgs := &ChangePasswordParams{
+			Password: pgtype.Text{String: args.Password, Valid: true},
+			ID:       args.UserID,
+		}
+		err := q.ChangePassword(ctx, *changePassArgs)
+		if err != nil {
+			return err
+		}
+
+		historyArgs := &CreatePasswordHistoryParams{
+			UserID:   args.UserID,
+			Password: args.Password,
+		}
+		_, err = q.CreatePasswordHistory(ctx, *historyArgs)
+		if err != nil {
+			return err
+		}
+
+		deleteArgs := &DeleteOldPasswordHistoryParams{
+			UserID: args.UserID,
+			Limit:  args.HistorySize,
+		}
+		if err = q.DeleteOldPasswordHistory(ctx, *deleteArgs); err != nil {
+			return err
+		}
+
+		if args.RevokeTokensBefore.IsZero() {
+			return nil
+		}
+		revokeArgs := RevokeUserTokensParams{UserID: args.UserID, RevokedBefore: args.RevokeTokensBefore}
+		return q.RevokeUserTokens(ctx, revokeArgs)
+	})
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"context"
+)
+
+func (store *SQLStore) TxUpdateNotificationPreferences(ctx context.Context, args []UpsertNotificationPreferenceParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		for _, arg := range args {
+			_, err := q.UpsertNotificationPreference(ctx, arg)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
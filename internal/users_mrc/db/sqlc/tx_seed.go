@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+type SeedTxParams struct {
+	Permissions []UpsertPermissionParams
+	Groups      []string
+}
+
+// TxSeed creates the missing groups and permissions, running it again changes nothing
+func (store *SQLStore) TxSeed(ctx context.Context, args *SeedTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		for _, arg := range args.Permissions {
+			if _, err := q.UpsertPermission(ctx, arg); err != nil {
+				return fmt.Errorf("permission %s: %w", arg.Codename.String, err)
+			}
+		}
+		for _, name := range args.Groups {
+			if _, err := q.UpsertGroup(ctx, name); err != nil {
+				return fmt.Errorf("group %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
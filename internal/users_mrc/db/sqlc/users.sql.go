@@ -11,6 +11,42 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const banUserByEmail = `-- name: BanUserByEmail :one
+UPDATE users
+SET
+    is_banned = $2
+WHERE email = $1
+    RETURNING id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy, lang_code
+`
+
+type BanUserByEmailParams struct {
+	Email    string      `json:"email"`
+	IsBanned pgtype.Bool `json:"is_banned"`
+}
+
+func (q *Queries) BanUserByEmail(ctx context.Context, arg BanUserByEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, banUserByEmail, arg.Email, arg.IsBanned)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.Password,
+		&i.IsDeleted,
+		&i.AuthSource,
+		&i.UpdatedAt,
+		&i.LastTokenUpdate,
+		&i.VerifiedEmail,
+		&i.UserType,
+		&i.IsBanned,
+		&i.DateJoined,
+		&i.Sexy,
+		&i.LangCode,
+	)
+	return i, err
+}
+
 const changePassword = `-- name: ChangePassword :exec
 UPDATE users
 SET
@@ -77,10 +113,11 @@ INSERT INTO users (
     first_name,
     auth_source,
     sexy,
+    lang_code,
     last_token_update
 ) VALUES
-    ($1, $2, $3, $4, $5, $6, $7, NOW())
-    RETURNING id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy
+    ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+    RETURNING id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy, lang_code
 `
 
 type CreateUserParams struct {
@@ -91,6 +128,7 @@ type CreateUserParams struct {
 	FirstName  pgtype.Text   `json:"first_name"`
 	AuthSource string        `json:"auth_source"`
 	Sexy       NullSexy      `json:"sexy"`
+	LangCode   string        `json:"lang_code"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -102,6 +140,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.FirstName,
 		arg.AuthSource,
 		arg.Sexy,
+		arg.LangCode,
 	)
 	var i User
 	err := row.Scan(
@@ -119,6 +158,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.IsBanned,
 		&i.DateJoined,
 		&i.Sexy,
+		&i.LangCode,
 	)
 	return i, err
 }
@@ -142,7 +182,7 @@ func (q *Queries) DeleteUserById(ctx context.Context, id pgtype.UUID) error {
 }
 
 const findUsers = `-- name: FindUsers :many
-SELECT u.id, u.email, u.first_name, u.last_name, u.password, u.is_deleted, u.auth_source, u.updated_at, u.last_token_update, u.verified_email, u.user_type, u.is_banned, u.date_joined, u.sexy
+SELECT u.id, u.email, u.first_name, u.last_name, u.password, u.is_deleted, u.auth_source, u.updated_at, u.last_token_update, u.verified_email, u.user_type, u.is_banned, u.date_joined, u.sexy, u.lang_code
 FROM users u
          JOIN user_groups ug ON u.id = ug.user_id
          JOIN groups g ON ug.group_id = g.id
@@ -187,6 +227,7 @@ func (q *Queries) FindUsers(ctx context.Context, arg FindUsersParams) ([]User, e
 			&i.IsBanned,
 			&i.DateJoined,
 			&i.Sexy,
+			&i.LangCode,
 		); err != nil {
 			return nil, err
 		}
@@ -328,7 +369,7 @@ func (q *Queries) GetAllUsersAndRoles(ctx context.Context, arg GetAllUsersAndRol
 }
 
 const getAllUsersByRole = `-- name: GetAllUsersByRole :many
-SELECT u.id, u.email, u.first_name, u.last_name, u.password, u.is_deleted, u.auth_source, u.updated_at, u.last_token_update, u.verified_email, u.user_type, u.is_banned, u.date_joined, u.sexy
+SELECT u.id, u.email, u.first_name, u.last_name, u.password, u.is_deleted, u.auth_source, u.updated_at, u.last_token_update, u.verified_email, u.user_type, u.is_banned, u.date_joined, u.sexy, u.lang_code
 FROM users u
          JOIN user_groups ug ON u.id = ug.user_id
          JOIN groups g ON ug.group_id = g.id
@@ -366,6 +407,7 @@ func (q *Queries) GetAllUsersByRole(ctx context.Context, arg GetAllUsersByRolePa
 			&i.IsBanned,
 			&i.DateJoined,
 			&i.Sexy,
+			&i.LangCode,
 		); err != nil {
 			return nil, err
 		}
@@ -403,6 +445,7 @@ SELECT
     u.date_joined,
     u.is_deleted,
     u.user_type,
+    u.lang_code,
     COALESCE(ARRAY_AGG(g.name), '{}') AS groups
 FROM users u
          LEFT JOIN user_groups ug ON u.id = ug.user_id
@@ -422,6 +465,7 @@ type GetUserAndGroupsByEmailRow struct {
 	DateJoined    pgtype.Timestamptz `json:"date_joined"`
 	IsDeleted     pgtype.Bool        `json:"is_deleted"`
 	UserType      NullUserTypes      `json:"user_type"`
+	LangCode      string             `json:"lang_code"`
 	Groups        interface{}        `json:"groups"`
 }
 
@@ -438,13 +482,14 @@ func (q *Queries) GetUserAndGroupsByEmail(ctx context.Context, email string) (Ge
 		&i.DateJoined,
 		&i.IsDeleted,
 		&i.UserType,
+		&i.LangCode,
 		&i.Groups,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy FROM users
+SELECT id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy, lang_code FROM users
 WHERE email = $1
 `
 
@@ -466,12 +511,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.IsBanned,
 		&i.DateJoined,
 		&i.Sexy,
+		&i.LangCode,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy FROM users
+SELECT id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy, lang_code FROM users
 WHERE id = $1
 `
 
@@ -493,6 +539,7 @@ func (q *Queries) GetUserById(ctx context.Context, id pgtype.UUID) (User, error)
 		&i.IsBanned,
 		&i.DateJoined,
 		&i.Sexy,
+		&i.LangCode,
 	)
 	return i, err
 }
@@ -636,7 +683,7 @@ SET
     verified_email = COALESCE($3, verified_email),
     updated_at = NOW()
 WHERE email = $4
-    RETURNING id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy
+    RETURNING id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy, lang_code
 `
 
 type UpdateUserByEmailParams struct {
@@ -669,6 +716,7 @@ func (q *Queries) UpdateUserByEmail(ctx context.Context, arg UpdateUserByEmailPa
 		&i.IsBanned,
 		&i.DateJoined,
 		&i.Sexy,
+		&i.LangCode,
 	)
 	return i, err
 }
@@ -679,15 +727,17 @@ SET
     first_name = COALESCE($1, first_name),
     last_name = COALESCE($2, last_name),
     verified_email = COALESCE($3, verified_email),
+    lang_code = COALESCE($4, lang_code),
     updated_at = NOW()
-WHERE id = $4
-    RETURNING id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy
+WHERE id = $5
+    RETURNING id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy, lang_code
 `
 
 type UpdateUserByIdParams struct {
 	FirstName     pgtype.Text `json:"first_name"`
 	LastName      pgtype.Text `json:"last_name"`
 	VerifiedEmail pgtype.Bool `json:"verified_email"`
+	LangCode      pgtype.Text `json:"lang_code"`
 	ID            pgtype.UUID `json:"id"`
 }
 
@@ -696,6 +746,7 @@ func (q *Queries) UpdateUserById(ctx context.Context, arg UpdateUserByIdParams)
 		arg.FirstName,
 		arg.LastName,
 		arg.VerifiedEmail,
+		arg.LangCode,
 		arg.ID,
 	)
 	var i User
@@ -714,13 +765,14 @@ func (q *Queries) UpdateUserById(ctx context.Context, arg UpdateUserByIdParams)
 		&i.IsBanned,
 		&i.DateJoined,
 		&i.Sexy,
+		&i.LangCode,
 	)
 	return i, err
 }
 
 const userExists = `-- name: UserExists :one
 SELECT EXISTS (
-    SELECT id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy FROM users WHERE email = $1
+    SELECT id, email, first_name, last_name, password, is_deleted, auth_source, updated_at, last_token_update, verified_email, user_type, is_banned, date_joined, sexy, lang_code FROM users WHERE email = $1
 )
 `
 
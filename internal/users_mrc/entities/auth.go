@@ -10,6 +10,23 @@ type RefreshTokenReq struct {
 }
 
 type ChangePasswordReq struct {
-	OldPassword string `json:"old_Password" validate:"required,password"`
+	OldPassword string `json:"old_Password" validate:"required"`
 	NewPassword string `json:"new_password" validate:"required,password"`
 }
+
+type LoginLinkReq struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type LoginLinkSignInReq struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type TokensResp struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AccessTokenResp struct {
+	AccessToken string `json:"access_token"`
+}
@@ -0,0 +1,21 @@
+package entities
+
+type NotificationPreference struct {
+	Channel  string `json:"channel" validate:"required,oneof=email sms in_app"`
+	Category string `json:"category" validate:"required,oneof=security job_alerts marketing messages"`
+	Enabled  bool   `json:"enabled"`
+}
+
+type NotificationPreferencesUpdate struct {
+	Preferences []NotificationPreference `json:"preferences" validate:"required,dive"`
+}
+
+type NotificationPreferencesResp struct {
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+type UnsubscribeInfo struct {
+	Email    string `json:"email"`
+	Channel  string `json:"channel"`
+	Category string `json:"category"`
+}
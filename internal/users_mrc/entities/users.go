@@ -8,8 +8,9 @@ import (
 type UserUpdate struct {
 	FirstName   string `json:"first_name"`
 	LastName    string `json:"last_name"`
-	Phone       int64  `json:"phone"`
-	CountryCode string `json:"country_code"`
+	Phone       int64  `json:"phone" validate:"omitempty,phone"`
+	CountryCode string `json:"country_code" validate:"omitempty,country_code"`
+	LangCode    string `json:"lang_code" validate:"omitempty,oneof=ru en kk"`
 }
 
 type UserPhone struct {
@@ -25,6 +26,7 @@ type UserDetail struct {
 	UserType      string    `json:"user_type"`
 	FirstName     string    `json:"first_name"`
 	LastName      string    `json:"last_name"`
+	LangCode      string    `json:"lang_code"`
 	AccountPhone  UserPhone `json:"phone"`
 }
 
@@ -38,6 +40,7 @@ func NewUserDetailResponse(user db.GetUserAndGroupsByEmailRow, phone db.Phone, r
 		UserType:      string(userType.UserTypes),
 		FirstName:     user.FirstName.String,
 		LastName:      user.LastName.String,
+		LangCode:      user.LangCode,
 	}
 	account.AccountPhone.Number = phone.Number
 	account.AccountPhone.CountryCode = phone.CountryCode
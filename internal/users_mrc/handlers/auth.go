@@ -1,18 +1,20 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog/log"
 	db "job_search_platform/internal/users_mrc/db/sqlc"
 	"job_search_platform/internal/users_mrc/entities"
 	"job_search_platform/internal/users_mrc/usecases"
+	"job_search_platform/pkg/database"
 	"job_search_platform/pkg/entities/common"
 	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/i18n"
 	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/openapi"
 	"job_search_platform/pkg/scheduler"
-	"net/http"
 	"time"
 )
 
@@ -25,27 +27,21 @@ func NewAuthHandler(usecase usecases.AuthUsecase, taskDistributor scheduler.Task
 	return AuthHandler{usecase: usecase, taskDistributor: taskDistributor}
 }
 
-func (handler *AuthHandler) SignUpUser(ctx *gin.Context) {
-	var payload *db.CreateOrdinaryUserTxParams
-	var err error
-	if err = ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(err, server.INVALID_DATA_ERR_CODE, nil))
-		return
-	}
+func (handler *AuthHandler) SignUpUser(ctx *gin.Context, payload *db.CreateOrdinaryUserTxParams) (*openapi.NoBody, error) {
+	payload.LangCode = i18n.Negotiate(payload.LangCode, ctx.GetHeader(i18n.AcceptLanguageHeader))
 	token, errCode, err := handler.usecase.CreateUser(
 		ctx,
 		payload,
 	)
 	if err != nil {
-		server.HandlerErr(ctx, errCode, err)
-		return
+		return nil, server.Error(errCode, err)
 	}
 
 	taskPayload := &common.PayloadSendVerifyEmail{
 		Email:     payload.Email,
 		FirstName: payload.FirstName,
 		LastName:  payload.LastName,
-		LangCode:  "ru",
+		LangCode:  payload.LangCode,
 		JWTToken:  token,
 	}
 	opts := []asynq.Option{
@@ -56,82 +52,113 @@ func (handler *AuthHandler) SignUpUser(ctx *gin.Context) {
 	err = handler.taskDistributor.DistributeTaskSendVerifyEmail(ctx, taskPayload, opts...)
 
 	if err != nil {
-		log.Info().Err(err).Msg(fmt.Sprintf("distribute task send verify email err: %v", err))
+		log.Ctx(ctx).Error().Err(err).Msg("cannot distribute task send verify email")
 	}
-	ctx.JSON(http.StatusOK, server.Response(nil, errCode, nil))
+	return nil, nil
 }
 
-func (handler *AuthHandler) EmailConfirmation(ctx *gin.Context) {
+func (handler *AuthHandler) EmailConfirmation(ctx *gin.Context, _ *openapi.NoBody) (*openapi.NoBody, error) {
 	token := ctx.Query("token")
 	if token == "" {
-		ctx.JSON(http.StatusBadRequest, server.Response(nil, server.INVALID_URL_PARAM_ERR_CODE, nil))
-		return
+		return nil, server.Error(server.INVALID_URL_PARAM_ERR_CODE, nil)
 	}
 	errCode, err := handler.usecase.EmailConfirmation(ctx, token)
 	if err != nil {
-		server.HandlerErr(ctx, errCode, err)
-		return
+		return nil, server.Error(errCode, err)
 	}
-	ctx.JSON(http.StatusOK, server.Response(nil, 0, nil))
+	return nil, nil
 }
 
-func (handler *AuthHandler) SignInUser(ctx *gin.Context) {
-	var payload *entities.SignInReq
-
-	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(err, server.INVALID_DATA_ERR_CODE, nil))
-		return
-	}
+func (handler *AuthHandler) SignInUser(ctx *gin.Context, payload *entities.SignInReq) (*entities.TokensResp, error) {
 	user, groups, errCode, err := handler.usecase.GetUser(ctx, payload)
 	if err != nil {
-		server.AuthHandlerErr(ctx, errCode, err)
-		return
+		return nil, server.AuthError(errCode, err)
 	}
+	return handler.tokens(user, groups)
+}
+
+// tokens issues the access/refresh pair, the gateway binds it to the session (ProxySignInReq)
+func (handler *AuthHandler) tokens(user db.User, groups []db.GetGroupsByUserIdRow) (*entities.TokensResp, error) {
 	accessToken, _, errCode, err := handler.usecase.CreateAccessAndRefreshToken(user, groups, "access")
 	if err != nil {
-		server.HandlerErr(ctx, errCode, err)
-		return
+		return nil, server.Error(errCode, err)
 	}
 	refreshToken, _, errCode, err := handler.usecase.CreateAccessAndRefreshToken(user, groups, "refresh")
 	if err != nil {
-		server.HandlerErr(ctx, errCode, err)
-		return
+		return nil, server.Error(errCode, err)
 	}
-	response := gin.H{"access_token": accessToken, "refresh_token": refreshToken}
-	ctx.JSON(http.StatusOK, server.Response(nil, errCode, response))
+	return &entities.TokensResp{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
-func (handler *AuthHandler) RefreshAccessToken(ctx *gin.Context) {
-	var payload *entities.RefreshTokenReq
-	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(err, server.INVALID_DATA_ERR_CODE, nil))
-		return
+func (handler *AuthHandler) RequestLoginLink(ctx *gin.Context, payload *entities.LoginLinkReq) (*openapi.NoBody, error) {
+	sessionId := ctx.GetHeader(server.SessionIdHeader)
+	token, user, expiresAt, errCode, err := handler.usecase.CreateLoginToken(ctx, payload.Email, sessionId)
+	// не раскрываем, зарегистрирован ли email
+	if errors.Is(err, database.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, server.Error(errCode, err)
 	}
 
+	taskPayload := &common.PayloadSendLoginLink{
+		Email:     user.Email,
+		Token:     token,
+		LangCode:  i18n.Negotiate(user.LangCode, ctx.GetHeader(i18n.AcceptLanguageHeader)),
+		FirstName: user.FirstName.String,
+		LastName:  user.LastName.String,
+		ExpiresAt: expiresAt,
+	}
+	opts := []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Deadline(expiresAt),
+		asynq.Queue(scheduler.QueueCritical),
+	}
+	err = handler.taskDistributor.DistributeTaskSendLoginLink(ctx, taskPayload, opts...)
+	if err != nil {
+		return nil, server.Error(server.UNKNOWN_ERROR_CODE, err)
+	}
+	return nil, nil
+}
+
+func (handler *AuthHandler) SignInByLoginLink(ctx *gin.Context, payload *entities.LoginLinkSignInReq) (*entities.TokensResp, error) {
+	sessionId := ctx.GetHeader(server.SessionIdHeader)
+	user, groups, errCode, err := handler.usecase.GetUserByLoginToken(ctx, payload.Token, sessionId)
+	if err != nil {
+		return nil, server.AuthError(errCode, err)
+	}
+	return handler.tokens(user, groups)
+}
+
+func (handler *AuthHandler) RefreshAccessToken(ctx *gin.Context, payload *entities.RefreshTokenReq) (*entities.AccessTokenResp, error) {
 	accessToken, errCode, err := handler.usecase.RefreshAccessToken(ctx, payload.RefreshToken)
 	if err != nil {
-		server.HandlerErr(ctx, errCode, err)
-		return
+		return nil, server.Error(errCode, err)
 	}
-	response := gin.H{"access_token": accessToken}
-	ctx.JSON(http.StatusOK, server.Response(nil, errCode, response))
+	return &entities.AccessTokenResp{AccessToken: accessToken}, nil
 }
 
-func (handler *AuthHandler) ChangePassword(ctx *gin.Context) {
-	var payload *entities.ChangePasswordReq
-	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(err, server.INVALID_DATA_ERR_CODE, nil))
-		return
+// Logout revokes the refresh token of the gateway session
+func (handler *AuthHandler) Logout(ctx *gin.Context, payload *entities.RefreshTokenReq) (*openapi.NoBody, error) {
+	jwtPayload, exists := jwt_token.GetJWTPayload(ctx)
+	if !exists {
+		return nil, server.Error(server.MISSING_JWT_TOKEN_ERR_CODE, nil)
+	}
+	errCode, err := handler.usecase.RevokeRefreshToken(ctx, payload.RefreshToken, jwtPayload.UserId)
+	if err != nil {
+		return nil, server.Error(errCode, err)
 	}
+	return nil, nil
+}
+
+func (handler *AuthHandler) ChangePassword(ctx *gin.Context, payload *entities.ChangePasswordReq) (*openapi.NoBody, error) {
 	jwtPayload, exists := jwt_token.GetJWTPayload(ctx)
 	if !exists {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(nil, server.UNKNOWN_ERROR_CODE, nil))
-		return
+		return nil, server.Error(server.MISSING_JWT_TOKEN_ERR_CODE, nil)
 	}
 	errCode, err := handler.usecase.ChangePassword(ctx, payload, jwtPayload.UserId)
 	if err != nil {
-		server.HandlerErr(ctx, errCode, err)
-		return
+		return nil, server.Error(errCode, err)
 	}
-	ctx.JSON(http.StatusOK, server.Response(nil, errCode, nil))
+	return nil, nil
 }
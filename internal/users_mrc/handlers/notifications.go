@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"job_search_platform/internal/users_mrc/entities"
+	"job_search_platform/internal/users_mrc/usecases"
+	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/openapi"
+)
+
+type NotificationsHandler struct {
+	usecase usecases.NotificationsUsecase
+}
+
+func NewNotificationsHandler(usecase usecases.NotificationsUsecase) NotificationsHandler {
+	return NotificationsHandler{usecase: usecase}
+}
+
+func (handler *NotificationsHandler) GetPreferences(ctx *gin.Context, _ *openapi.NoBody) (*entities.NotificationPreferencesResp, error) {
+	jwtPayload, exists := jwt_token.GetJWTPayload(ctx)
+	if !exists {
+		return nil, server.Error(server.MISSING_JWT_TOKEN_ERR_CODE, nil)
+	}
+	preferences, errCode, err := handler.usecase.GetPreferences(ctx, jwtPayload.UserId)
+	if err != nil {
+		return nil, server.Error(errCode, err)
+	}
+	return &entities.NotificationPreferencesResp{Preferences: preferences}, nil
+}
+
+func (handler *NotificationsHandler) UpdatePreferences(ctx *gin.Context, payload *entities.NotificationPreferencesUpdate) (*openapi.NoBody, error) {
+	jwtPayload, exists := jwt_token.GetJWTPayload(ctx)
+	if !exists {
+		return nil, server.Error(server.MISSING_JWT_TOKEN_ERR_CODE, nil)
+	}
+	errCode, err := handler.usecase.UpdatePreferences(ctx, jwtPayload.UserId, *payload)
+	if err != nil {
+		return nil, server.Error(errCode, err)
+	}
+	return nil, nil
+}
+
+func (handler *NotificationsHandler) GetUnsubscribeInfo(ctx *gin.Context, _ *openapi.NoBody) (*entities.UnsubscribeInfo, error) {
+	token := ctx.Query("token")
+	if token == "" {
+		return nil, server.Error(server.INVALID_URL_PARAM_ERR_CODE, nil)
+	}
+	info, errCode, err := handler.usecase.GetUnsubscribeInfo(token)
+	if err != nil {
+		return nil, server.Error(errCode, err)
+	}
+	return &info, nil
+}
+
+// Unsubscribe is the RFC 8058 one-click endpoint, mail clients POST here without any session
+func (handler *NotificationsHandler) Unsubscribe(ctx *gin.Context, _ *openapi.NoBody) (*openapi.NoBody, error) {
+	token := ctx.Query("token")
+	if token == "" {
+		return nil, server.Error(server.INVALID_URL_PARAM_ERR_CODE, nil)
+	}
+	errCode, err := handler.usecase.Unsubscribe(ctx, token)
+	if err != nil {
+		return nil, server.Error(errCode, err)
+	}
+	return nil, nil
+}
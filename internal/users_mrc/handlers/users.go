@@ -6,7 +6,7 @@ import (
 	"job_search_platform/internal/users_mrc/usecases"
 	"job_search_platform/pkg/helpers/server"
 	"job_search_platform/pkg/jwt_token"
-	"net/http"
+	"job_search_platform/pkg/openapi"
 )
 
 type UsersHandler struct {
@@ -17,22 +17,14 @@ func NewUsersHandler(usecase usecases.UsersUsecase) UsersHandler {
 	return UsersHandler{usecase: usecase}
 }
 
-func (handler *UsersHandler) UpdateUser(ctx *gin.Context) {
-	var payload *entities.UserUpdate
-	var err error
-	if err = ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(err, server.INVALID_DATA_ERR_CODE, nil))
-		return
-	}
+func (handler *UsersHandler) UpdateUser(ctx *gin.Context, payload *entities.UserUpdate) (*openapi.NoBody, error) {
 	jwtPayload, exists := jwt_token.GetJWTPayload(ctx)
 	if !exists {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, server.Response(nil, server.UNKNOWN_ERROR_CODE, nil))
-		return
+		return nil, server.Error(server.MISSING_JWT_TOKEN_ERR_CODE, nil)
 	}
 	errCode, err := handler.usecase.UpdateUser(ctx, *payload, jwtPayload.UserId)
 	if err != nil {
-		server.HandlerErr(ctx, errCode, err)
-		return
+		return nil, server.Error(errCode, err)
 	}
-	ctx.JSON(http.StatusOK, server.Response(nil, errCode, nil))
+	return nil, nil
 }
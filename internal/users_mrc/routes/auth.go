@@ -1,8 +1,8 @@
 package routes
 
 import (
-	"github.com/gin-gonic/gin"
 	"job_search_platform/internal/users_mrc/handlers"
+	"job_search_platform/pkg/openapi"
 )
 
 type AuthRouter struct {
@@ -13,10 +13,32 @@ func NewAuthRouter(handler handlers.AuthHandler) *AuthRouter {
 	return &AuthRouter{handler: handler}
 }
 
-func (r *AuthRouter) InitAuthRouter(public *gin.RouterGroup, private *gin.RouterGroup) {
-	public.POST("/sign-up", r.handler.SignUpUser)
-	public.GET("/email-confirmation", r.handler.EmailConfirmation)
-	public.POST("/sign-in", r.handler.SignInUser)
-	public.POST("/refresh-token", r.handler.RefreshAccessToken)
-	private.POST("/change-password", r.handler.ChangePassword)
+func (r *AuthRouter) InitAuthRouter(public *openapi.Group, private *openapi.Group) {
+	public.POST("/sign-up", openapi.Typed(r.handler.SignUpUser), openapi.Op{
+		Summary:     "Sign up",
+		Description: "Creates the user and sends the email confirmation link",
+	})
+	public.GET("/email-confirmation", openapi.Typed(r.handler.EmailConfirmation), openapi.Op{
+		Summary: "Confirm the email",
+		Query:   []openapi.Param{{Name: "token", Description: "token from the confirmation link", Required: true}},
+	})
+	public.POST("/sign-in", openapi.Typed(r.handler.SignInUser), openapi.Op{
+		Summary: "Sign in with the login and password",
+	})
+	public.POST("/refresh-token", openapi.Typed(r.handler.RefreshAccessToken), openapi.Op{
+		Summary: "Issue a new access token",
+	})
+	public.POST("/magic-link", openapi.Typed(r.handler.RequestLoginLink), openapi.Op{
+		Summary:     "Send a sign-in link",
+		Description: "Answers success for unknown emails too",
+	})
+	public.POST("/magic-link/sign-in", openapi.Typed(r.handler.SignInByLoginLink), openapi.Op{
+		Summary: "Sign in with the token from the link",
+	})
+	private.POST("/change-password", openapi.Typed(r.handler.ChangePassword), openapi.Op{
+		Summary: "Change the password",
+	})
+	private.POST("/logout", openapi.Typed(r.handler.Logout), openapi.Op{
+		Summary: "Revoke the refresh token",
+	})
 }
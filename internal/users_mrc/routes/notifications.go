@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"job_search_platform/internal/users_mrc/handlers"
+	"job_search_platform/pkg/openapi"
+)
+
+type NotificationsRouter struct {
+	handler handlers.NotificationsHandler
+}
+
+func NewNotificationsRouter(handler handlers.NotificationsHandler) *NotificationsRouter {
+	return &NotificationsRouter{handler: handler}
+}
+
+func (r *NotificationsRouter) InitNotificationsRouter(public *openapi.Group, private *openapi.Group) {
+	unsubscribeToken := []openapi.Param{{Name: "token", Description: "signed token from the unsubscribe link", Required: true}}
+	public.GET("/unsubscribe", openapi.Typed(r.handler.GetUnsubscribeInfo), openapi.Op{
+		Summary: "Describe the unsubscribe link",
+		Query:   unsubscribeToken,
+	})
+	public.POST("/unsubscribe", openapi.Typed(r.handler.Unsubscribe), openapi.Op{
+		Summary:     "Unsubscribe",
+		Description: "RFC 8058 one-click unsubscribe",
+		Query:       unsubscribeToken,
+	})
+	private.GET("/notifications", openapi.Typed(r.handler.GetPreferences), openapi.Op{
+		Summary: "Get the notification preferences",
+	})
+	private.PUT("/notifications", openapi.Typed(r.handler.UpdatePreferences), openapi.Op{
+		Summary: "Update the notification preferences",
+	})
+}
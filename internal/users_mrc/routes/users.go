@@ -1,8 +1,8 @@
 package routes
 
 import (
-	"github.com/gin-gonic/gin"
 	"job_search_platform/internal/users_mrc/handlers"
+	"job_search_platform/pkg/openapi"
 )
 
 type UsersRouter struct {
@@ -13,6 +13,8 @@ func NewUsersRouter(handler handlers.UsersHandler) *UsersRouter {
 	return &UsersRouter{handler: handler}
 }
 
-func (r *UsersRouter) InitUsersRouter(public *gin.RouterGroup, private *gin.RouterGroup) {
-	private.POST("/update", r.handler.UpdateUser)
+func (r *UsersRouter) InitUsersRouter(public *openapi.Group, private *openapi.Group) {
+	private.POST("/update", openapi.Typed(r.handler.UpdateUser), openapi.Op{
+		Summary: "Update the profile",
+	})
 }
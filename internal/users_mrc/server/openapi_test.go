@@ -0,0 +1,22 @@
+package server
+
+import (
+	"job_search_platform/pkg/openapi"
+	"path/filepath"
+	"testing"
+)
+
+// committedSpec is the document the gateway and the clients are built against
+var committedSpec = filepath.Join("..", "..", "..", "api", "openapi", "users_mrc.json")
+
+// TestOpenAPIContract fails when the types the handlers bind and return, or the set of routes,
+// drift from the committed specification. Regenerate it with `users_mrc openapi -o api/openapi/users_mrc.json`.
+func TestOpenAPIContract(t *testing.T) {
+	doc, err := OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = openapi.Check(committedSpec, doc); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,34 +4,57 @@ import (
 	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/pkg/errors"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/rs/zerolog"
 	db "job_search_platform/internal/users_mrc/db/sqlc"
 	"job_search_platform/internal/users_mrc/handlers"
 	"job_search_platform/internal/users_mrc/routes"
 	"job_search_platform/internal/users_mrc/usecases"
+	"job_search_platform/pkg/app"
+	"job_search_platform/pkg/apperr"
 	"job_search_platform/pkg/config"
+	"job_search_platform/pkg/health"
+	"job_search_platform/pkg/helpers/crypto"
+	server2 "job_search_platform/pkg/helpers/server"
 	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/metrics"
 	"job_search_platform/pkg/middleware"
+	"job_search_platform/pkg/notifications"
+	"job_search_platform/pkg/openapi"
+	"job_search_platform/pkg/password_policy"
 	"job_search_platform/pkg/scheduler"
+	"job_search_platform/pkg/tracing"
+	"job_search_platform/pkg/validation"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 	"time"
 )
 
 type Server struct {
-	config      config.Config
-	store       db.Store
-	router      *gin.Engine
-	tokenMaker  jwt_token.Maker
-	distributor scheduler.TaskDistributor
-	httpServer  *http.Server
-	logger      zerolog.Logger
+	config         config.UsersMrc
+	store          db.Store
+	router         *gin.Engine
+	tokenMaker     jwt_token.Maker
+	passwordPolicy *password_policy.Policy
+	passwordHasher crypto.PasswordHasher
+	distributor    scheduler.TaskDistributor
+	httpServer     *http.Server
+	health         *health.Health
+	logger         zerolog.Logger
+	spec           *openapi.Spec
+
+	unsubscribeSigner *notifications.UnsubscribeSigner
+	identitySigner    *middleware.IdentitySigner
 }
 
-func NewServer(config config.Config, store db.Store, distributor scheduler.TaskDistributor, logger zerolog.Logger) (*Server, error) {
+func NewServer(
+	config config.UsersMrc,
+	store db.Store,
+	distributor scheduler.TaskDistributor,
+	unsubscribeSigner *notifications.UnsubscribeSigner,
+	health *health.Health,
+	logger zerolog.Logger,
+) (*Server, error) {
 	tokenMaker, err := jwt_token.NewJWTMaker(
 		config.TokenSymmetricKey,
 		config.AccessTokenExpiresIn,
@@ -40,13 +63,39 @@ func NewServer(config config.Config, store db.Store, distributor scheduler.TaskD
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	passwordPolicy, err := password_policy.NewPolicy(config.PasswordPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password policy: %w", err)
+	}
+	passwordHasher, err := crypto.NewPasswordHasher(config.PasswordHashing)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	// без подписи заголовков пользователь берется только из bearer токена
+	var identitySigner *middleware.IdentitySigner
+	if config.GatewayIdentityHeaders {
+		identitySigner, err = middleware.NewIdentitySigner(config.GatewayIdentitySecret, config.GatewayIdentityMaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create identity signer: %w", err)
+		}
+	}
+	err = registerValidations(passwordPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("cannot register validations: %w", err)
+	}
 
 	server := &Server{
-		config:      config,
-		store:       store,
-		tokenMaker:  tokenMaker,
-		distributor: distributor,
-		logger:      logger,
+		config:         config,
+		store:          store,
+		tokenMaker:     tokenMaker,
+		passwordPolicy: passwordPolicy,
+		passwordHasher: passwordHasher,
+		distributor:    distributor,
+		health:         health,
+		logger:         logger,
+
+		unsubscribeSigner: unsubscribeSigner,
+		identitySigner:    identitySigner,
 	}
 	server.setupRouter()
 	server.httpServer = &http.Server{
@@ -59,85 +108,126 @@ func NewServer(config config.Config, store db.Store, distributor scheduler.TaskD
 	return server, nil
 }
 
+// userTypeTag accepts the values of the user_types enum
+const userTypeTag = "user_type"
+
+var userTypes = []string{string(db.UserTypesCompany), string(db.UserTypesJobSeeker)}
+
+// registerValidations makes gin check the `validate` struct tags of the entities with the custom rules,
+// failed fields are returned in the errors of the problem response
+func registerValidations(passwordPolicy *password_policy.Policy) error {
+	validate := validation.New()
+	validate.RegisterEnum(userTypeTag, userTypes...)
+	if err := password_policy.RegisterValidation(validate, passwordPolicy); err != nil {
+		return err
+	}
+	binding.Validator = validate
+	return nil
+}
+
 func (server *Server) setupRouter() {
-	router := gin.Default()
+	router := gin.New()
+	// контекст gin отдает значения контекста запроса, в том числе текущий span
+	router.ContextWithFallback = true
 	//router.Use(middleware.OpenCORSMiddleware())
 	//router.Use(middleware.HandleSessionMiddleware(server.store))
-	router.Use(gin.Recovery())
-	router.Use(gin.Logger())
+	router.Use(gin.CustomRecovery(func(ctx *gin.Context, recovered interface{}) {
+		apperr.Abort(ctx, apperr.Internal("panic", fmt.Errorf("%v", recovered)))
+	}))
+	router.Use(middleware.RequestId())
+	router.Use(tracing.Middleware("users_mrc", metrics.Route))
+	router.Use(middleware.RequestLogger(server.logger))
+	router.Use(metrics.Middleware("users_mrc"))
+	router.Use(middleware.Deadline())
 	router.NoRoute(func(ctx *gin.Context) {
-		ctx.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Route %s not found", ctx.Request.URL)})
+		server2.AbortErr(ctx, server2.ROUTE_NOT_FOUND_ERR_CODE, fmt.Errorf("route %s not found", ctx.Request.URL))
 	})
 	router.GET("/ping", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
+	server.health.Register(router)
+	// спецификация собирается при регистрации маршрутов, gateway объединяет ее с другими сервисами
+	server.spec = openapi.NewSpec("users_mrc", "v1")
+	server.spec.RegisterEnum(userTypeTag, userTypes...)
+	router.GET(openapi.SpecPath, server.spec.Handler())
 
 	api := router.Group("api")
 	v1 := api.Group("/v1")
 	//adminGroup := v1.Group("/admin")
 	server.setupAuthRoutes(v1)
 	server.setupUsersRoutes(v1)
+	server.setupNotificationsRoutes(v1)
 	server.router = router
 }
 
 func (server *Server) setupAuthRoutes(rg *gin.RouterGroup) {
-	jwtDeserializer := middleware.JWTDeserializer(server.tokenMaker)
-	usecase := usecases.NewAuthUsecase(server.store, server.tokenMaker)
+	identity := middleware.Identity(server.identitySigner, server.tokenMaker)
+	usecase := usecases.NewAuthUsecase(
+		server.store, server.tokenMaker, server.passwordPolicy, server.passwordHasher, server.config)
 	handler := handlers.NewAuthHandler(usecase, server.distributor)
 	route := routes.NewAuthRouter(handler)
 	router := rg.Group("/auth")
 	public := router.Group("/public")
 	private := router.Group("/private")
-	private.Use(jwtDeserializer)
-	route.InitAuthRouter(public, private)
+	private.Use(identity)
+	route.InitAuthRouter(server.spec.Group(public, "auth", false), server.spec.Group(private, "auth", true))
 }
 
 func (server *Server) setupUsersRoutes(rg *gin.RouterGroup) {
-	jwtDeserializer := middleware.JWTDeserializer(server.tokenMaker)
+	identity := middleware.Identity(server.identitySigner, server.tokenMaker)
 	usecase := usecases.NewUsersUsecase(server.store)
 	handler := handlers.NewUsersHandler(usecase)
 	route := routes.NewUsersRouter(handler)
 	router := rg.Group("/users")
 	public := router.Group("/public")
 	private := router.Group("/private")
-	private.Use(jwtDeserializer)
-	route.InitUsersRouter(public, private)
+	private.Use(identity)
+	route.InitUsersRouter(server.spec.Group(public, "users", false), server.spec.Group(private, "users", true))
 }
 
-func (server *Server) Start() error {
-	go func() {
-		server.logger.Info().Msg(fmt.Sprintf("Starting server on %s\n", server.httpServer.Addr))
-		if err := server.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			server.logger.Fatal().Err(err).Msg("Failed to start server")
-		}
-	}()
-
-	// Ожидание сигналов завершения
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	<-quit
-	server.logger.Info().Msg("Shutting down server...")
-
-	// Контекст с таймаутом для завершения
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (server *Server) setupNotificationsRoutes(rg *gin.RouterGroup) {
+	identity := middleware.Identity(server.identitySigner, server.tokenMaker)
+	usecase := usecases.NewNotificationsUsecase(server.store, server.unsubscribeSigner)
+	handler := handlers.NewNotificationsHandler(usecase)
+	route := routes.NewNotificationsRouter(handler)
+	router := rg.Group("/users")
+	public := router.Group("/public")
+	private := router.Group("/private")
+	private.Use(identity)
+	route.InitNotificationsRouter(
+		server.spec.Group(public, "notifications", false), server.spec.Group(private, "notifications", true))
+}
 
-	if err := server.httpServer.Shutdown(ctx); err != nil {
-		server.logger.Fatal().Err(err).Msg("Server forced to shutdown")
+// OpenAPI builds the specification from the route registration. The handlers are not called,
+// so the server is built without dependencies. Routes registered past openapi.Group are an error.
+func OpenAPI() (*openapi.Document, error) {
+	gin.SetMode(gin.ReleaseMode)
+	server := &Server{health: health.New(0)}
+	server.setupRouter()
+	doc := server.spec.Document()
+	if undocumented := openapi.Undocumented(server.router.Routes(), doc, "/api/"); len(undocumented) > 0 {
+		return nil, fmt.Errorf("routes without the specification: %s", strings.Join(undocumented, ", "))
 	}
-	server.logger.Info().Msg("Server exited gracefully")
-	return nil
+	return doc, nil
 }
 
-func RunGinServer(config config.Config, store db.Store, distributor scheduler.TaskDistributor, logger zerolog.Logger) error {
-	server, err := NewServer(config, store, distributor, logger)
-	if err != nil {
-		return err
+// Hook runs the http server in the application. On stop /readyz turns 503 for SHUTDOWN_DRAIN_DELAY,
+// so that load balancers stop sending requests, then the active requests are finished.
+func (server *Server) Hook() app.Hook {
+	hook := app.HttpServer("http server", server.httpServer)
+	run := hook.OnRun
+	hook.OnRun = func(ctx context.Context) error {
+		server.logger.Info().Msgf("Starting server on %s", server.httpServer.Addr)
+		return run(ctx)
 	}
-	err = server.Start()
-	if err != nil {
-		return err
+	hook.OnStop = func(ctx context.Context) error {
+		server.health.SetDraining()
+		select {
+		case <-time.After(server.config.ShutdownDrainDelay):
+		case <-ctx.Done():
+		}
+		return server.httpServer.Shutdown(ctx)
 	}
-	return nil
+	hook.StopTimeout = server.config.ShutdownDrainDelay + server.config.ShutdownTimeout
+	return hook
 }
@@ -2,25 +2,55 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
 	db "job_search_platform/internal/users_mrc/db/sqlc"
 	"job_search_platform/internal/users_mrc/entities"
+	"job_search_platform/pkg/config"
 	"job_search_platform/pkg/database"
 	"job_search_platform/pkg/entities/common"
 	"job_search_platform/pkg/helpers/crypto"
 	"job_search_platform/pkg/helpers/server"
 	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/password_policy"
+	"time"
+)
+
+const loginTokenSize = 32
+
+var (
+	ErrInvalidLoginToken = errors.New("login link is invalid or expired")
+	ErrLoginTokenSession = errors.New("login link was requested from another session")
+	ErrLoginLinkSession  = errors.New("login link must be requested from a gateway session")
+	ErrTokenRevoked      = errors.New("token has been revoked")
+	ErrUserBanned        = errors.New("user is banned")
 )
 
 type AuthUsecase struct {
-	store      db.Store
-	tokenMaker jwt_token.Maker
+	store          db.Store
+	tokenMaker     jwt_token.Maker
+	passwordPolicy *password_policy.Policy
+	passwordHasher crypto.PasswordHasher
+	config         config.UsersMrc
 }
 
-func NewAuthUsecase(store db.Store, tokenMaker jwt_token.Maker) AuthUsecase {
-	return AuthUsecase{store: store, tokenMaker: tokenMaker}
+func NewAuthUsecase(
+	store db.Store,
+	tokenMaker jwt_token.Maker,
+	passwordPolicy *password_policy.Policy,
+	passwordHasher crypto.PasswordHasher,
+	config config.UsersMrc,
+) AuthUsecase {
+	return AuthUsecase{
+		store:          store,
+		tokenMaker:     tokenMaker,
+		passwordPolicy: passwordPolicy,
+		passwordHasher: passwordHasher,
+		config:         config,
+	}
 }
 
 func GetUserRoles(groups []db.GetGroupsByUserIdRow) []string {
@@ -55,7 +85,15 @@ func (uc *AuthUsecase) CreateUser(ctx context.Context, args *db.CreateOrdinaryUs
 	if userExists {
 		return token, server.USER_EXISTS_ERR_CODE, fmt.Errorf("user with email %s already exists", args.Email)
 	}
-	err = uc.store.TxCreateUser(ctx, args)
+	err = uc.passwordPolicy.Validate(args.Password1, args.Email, args.FirstName, args.LastName)
+	if err != nil {
+		return token, uc.passwordPolicy.GetErrorCode(err), err
+	}
+	hashPass, err := uc.passwordHasher.HashPassword(args.Password1)
+	if err != nil {
+		return token, server.PASSWORD_HASHING_ERR_CODE, err
+	}
+	err = uc.store.TxCreateUser(ctx, args, hashPass)
 	if err != nil {
 		return token, database.ErrorCode(err), err
 	}
@@ -63,9 +101,37 @@ func (uc *AuthUsecase) CreateUser(ctx context.Context, args *db.CreateOrdinaryUs
 		Email: args.Email,
 	}
 	token, _, err = uc.tokenMaker.CreateToken(user, "access")
+	if err != nil {
+		return token, uc.tokenMaker.GetErrorCode(err), err
+	}
 	return token, server.SUCCESS_CODE, nil
 }
 
+// CreateStaffUser creates a user in the groups with the verified email, platformctl creates the first administrator with it
+func (uc *AuthUsecase) CreateStaffUser(
+	ctx context.Context, args *db.CreateStaffUserTxParams, groups ...string) (user db.User, statusCode int32, err error) {
+	userExists, err := uc.store.UserExists(ctx, args.Email)
+	if err != nil {
+		return user, database.ErrorCode(err), err
+	}
+	if userExists {
+		return user, server.USER_EXISTS_ERR_CODE, fmt.Errorf("user with email %s already exists", args.Email)
+	}
+	err = uc.passwordPolicy.Validate(args.Password1, args.Email, args.FirstName, args.LastName)
+	if err != nil {
+		return user, uc.passwordPolicy.GetErrorCode(err), err
+	}
+	hashPass, err := uc.passwordHasher.HashPassword(args.Password1)
+	if err != nil {
+		return user, server.PASSWORD_HASHING_ERR_CODE, err
+	}
+	user, err = uc.store.TxCreateStaffUser(ctx, args, hashPass, groups...)
+	if err != nil {
+		return user, database.ErrorCode(err), err
+	}
+	return user, server.SUCCESS_CODE, nil
+}
+
 func (uc *AuthUsecase) CreateAccessAndRefreshToken(user db.User, groups []db.GetGroupsByUserIdRow, tokenType string) (string, *jwt_token.Payload, int32, error) {
 	var payload *jwt_token.Payload
 	var err error
@@ -77,6 +143,7 @@ func (uc *AuthUsecase) CreateAccessAndRefreshToken(user db.User, groups []db.Get
 		Email:    user.Email,
 		Groups:   groupsNames,
 		UserType: string(userType),
+		LangCode: user.LangCode,
 	}
 	tokenStr, payload, err = uc.tokenMaker.CreateToken(userResp, tokenType)
 	if err != nil {
@@ -97,11 +164,105 @@ func (uc *AuthUsecase) GetUser(
 		return user, groups, database.ErrorCode(err), err
 	}
 
-	err = crypto.ComparePassword(user.Password, args.Password)
+	err = uc.passwordHasher.ComparePassword(user.Password, args.Password)
 	if err != nil {
 		return user, groups, server.INCORRECT_PASSWORD_ERR_CODE, err
 	}
+	// блокировка проверяется после пароля, чтобы не раскрывать ее по одному email
+	if user.IsBanned.Bool {
+		return user, groups, server.USER_BANNED_ERR_CODE, ErrUserBanned
+	}
+	uc.rehashPassword(ctx, user, args.Password)
+
+	return user, groups, server.SUCCESS_CODE, nil
+}
+
+// rehashPassword upgrades a hash made with an outdated algorithm or parameters,
+// sign-in must not fail because of it, so errors are only logged
+func (uc *AuthUsecase) rehashPassword(ctx context.Context, user db.User, password string) {
+	if !uc.passwordHasher.NeedsRehash(user.Password) {
+		return
+	}
+	hashPass, err := uc.passwordHasher.HashPassword(password)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("user_id", uuid.UUID(user.ID.Bytes).String()).Msg("cannot rehash password")
+		return
+	}
+	changePassArgs := db.ChangePasswordParams{
+		Password: pgtype.Text{String: hashPass, Valid: true},
+		ID:       user.ID,
+	}
+	err = uc.store.ChangePassword(ctx, changePassArgs)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("user_id", uuid.UUID(user.ID.Bytes).String()).Msg("cannot store rehashed password")
+	}
+}
+
+// CreateLoginToken creates a single-use login token, only its hash is stored.
+// sessionId is the gateway session that requested the link, it is checked on sign-in if MagicLinkBindSession is set.
+// With the binding on a request without a session is rejected, the binding does not depend on the route table.
+func (uc *AuthUsecase) CreateLoginToken(
+	ctx context.Context, email string, sessionId string) (token string, user db.User, expiresAt time.Time, statusCode int32, err error) {
+	// до поиска пользователя, чтобы ответ не зависел от того, зарегистрирован ли email
+	if uc.config.MagicLinkBindSession && sessionId == "" {
+		return token, user, expiresAt, server.SESSION_NOT_FOUND_ERR_CODE, ErrLoginLinkSession
+	}
+	user, err = uc.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		return token, user, expiresAt, database.ErrorCode(err), err
+	}
+	token, err = crypto.GenerateToken(loginTokenSize)
+	if err != nil {
+		return token, user, expiresAt, server.GENERATE_JWT_TOKEN_ERR_CODE, err
+	}
+	expiresAt = time.Now().Add(uc.config.MagicLinkExpiresIn)
+	loginTokenArgs := &db.CreateLoginTokenParams{
+		UserID:    user.ID,
+		TokenHash: crypto.HashToken(token),
+		SessionID: pgtype.Text{String: sessionId, Valid: sessionId != ""},
+		ExpiresAt: expiresAt,
+	}
+	_, err = uc.store.CreateLoginToken(ctx, *loginTokenArgs)
+	if err != nil {
+		return token, user, expiresAt, database.ErrorCode(err), err
+	}
+	return token, user, expiresAt, server.SUCCESS_CODE, nil
+}
+
+// GetUserByLoginToken consumes the login token and returns the same data as GetUser does for the password sign-in
+func (uc *AuthUsecase) GetUserByLoginToken(
+	ctx context.Context, token string, sessionId string) (user db.User, groups []db.GetGroupsByUserIdRow, statusCode int32, err error) {
+	tokenHash := crypto.HashToken(token)
+	loginToken, err := uc.store.GetActiveLoginToken(ctx, tokenHash)
+	if errors.Is(err, database.ErrRecordNotFound) {
+		return user, groups, server.LOGIN_TOKEN_INVALID_ERR_CODE, ErrInvalidLoginToken
+	}
+	if err != nil {
+		return user, groups, database.ErrorCode(err), err
+	}
+	if uc.config.MagicLinkBindSession && (!loginToken.SessionID.Valid || loginToken.SessionID.String != sessionId) {
+		return user, groups, server.LOGIN_TOKEN_SESSION_ERR_CODE, ErrLoginTokenSession
+	}
+	// повторная проверка внутри UPDATE защищает от одновременного использования ссылки
+	_, err = uc.store.UseLoginToken(ctx, tokenHash)
+	if errors.Is(err, database.ErrRecordNotFound) {
+		return user, groups, server.LOGIN_TOKEN_INVALID_ERR_CODE, ErrInvalidLoginToken
+	}
+	if err != nil {
+		return user, groups, database.ErrorCode(err), err
+	}
 
+	user, err = uc.store.GetUserById(ctx, loginToken.UserID)
+	if err != nil {
+		return user, groups, database.ErrorCode(err), err
+	}
+	if user.IsBanned.Bool {
+		return user, groups, server.USER_BANNED_ERR_CODE, ErrUserBanned
+	}
+	groups, err = uc.store.GetGroupsByUserId(ctx, user.ID)
+	if err != nil {
+		return user, groups, database.ErrorCode(err), err
+	}
 	return user, groups, server.SUCCESS_CODE, nil
 }
 
@@ -112,10 +273,29 @@ func (uc *AuthUsecase) RefreshAccessToken(
 	if err != nil {
 		return "", server.TOKEN_VALIDATION_ERR_CODE, err
 	}
+	revoked, err := uc.store.IsTokenRevoked(ctx, pgtype.UUID{Bytes: sub.ID, Valid: true})
+	if err != nil {
+		return "", database.ErrorCode(err), err
+	}
+	if !revoked {
+		revoked, err = uc.store.AreUserTokensRevoked(ctx, db.AreUserTokensRevokedParams{
+			UserID:        pgtype.UUID{Bytes: sub.UserId, Valid: true},
+			RevokedBefore: sub.IssuedAt,
+		})
+		if err != nil {
+			return "", database.ErrorCode(err), err
+		}
+	}
+	if revoked {
+		return "", server.TOKEN_REVOKED_ERR_CODE, ErrTokenRevoked
+	}
 	user, err := uc.store.GetUserByEmail(ctx, sub.Email)
 	if err != nil {
 		return "", database.ErrorCode(err), err
 	}
+	if user.IsBanned.Bool {
+		return "", server.USER_BANNED_ERR_CODE, ErrUserBanned
+	}
 	groups, err := uc.store.GetGroupsByUserId(ctx, user.ID)
 	if err != nil {
 		return "", database.ErrorCode(err), err
@@ -124,9 +304,11 @@ func (uc *AuthUsecase) RefreshAccessToken(
 		groupsNames = append(groupsNames, group.GroupName)
 	}
 	userResp := common.UserResponse{
-		UserId: user.ID.Bytes,
-		Email:  user.Email,
-		Groups: groupsNames,
+		UserId:   user.ID.Bytes,
+		Email:    user.Email,
+		Groups:   groupsNames,
+		UserType: string(user.UserType.UserTypes),
+		LangCode: user.LangCode,
 	}
 	accessToken, _, err = uc.tokenMaker.CreateToken(userResp, "access")
 	if err != nil {
@@ -139,6 +321,26 @@ func (uc *AuthUsecase) RefreshAccessToken(
 	return accessToken, server.SUCCESS_CODE, nil
 }
 
+// RevokeRefreshToken is called by the gateway on logout, the token stays revoked until it expires
+func (uc *AuthUsecase) RevokeRefreshToken(ctx context.Context, refreshToken string, userId uuid.UUID) (int32, error) {
+	sub, err := uc.tokenMaker.VerifyToken(refreshToken)
+	if err != nil {
+		return uc.tokenMaker.GetErrorCode(err), err
+	}
+	if sub.UserId != userId {
+		return server.TOKEN_VALIDATION_ERR_CODE, errors.New("refresh token belongs to another user")
+	}
+	err = uc.store.RevokeToken(ctx, db.RevokeTokenParams{
+		TokenID:   pgtype.UUID{Bytes: sub.ID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: sub.UserId, Valid: true},
+		ExpiresAt: sub.ExpiredAt,
+	})
+	if err != nil {
+		return database.ErrorCode(err), err
+	}
+	return server.SUCCESS_CODE, nil
+}
+
 func (uc *AuthUsecase) ChangePassword(
 	ctx context.Context,
 	payload *entities.ChangePasswordReq,
@@ -148,19 +350,74 @@ func (uc *AuthUsecase) ChangePassword(
 	if err != nil {
 		return database.ErrorCode(err), err
 	}
-	err = crypto.ComparePassword(user.Password, payload.OldPassword)
+	err = uc.passwordHasher.ComparePassword(user.Password, payload.OldPassword)
 	if err != nil {
 		return server.INCORRECT_PASSWORD_ERR_CODE, err
 	}
-	hashPass := crypto.HashPassword(payload.NewPassword)
+	return uc.setPassword(ctx, user, payload.NewPassword, time.Time{})
+}
 
-	changePassArgs := db.ChangePasswordParams{
-		Password: pgtype.Text{String: hashPass, Valid: true},
-		ID:       pgtype.UUID{Bytes: userId, Valid: true},
+// ResetPassword sets a new password without checking the old one, the policy and history still apply.
+// The refresh tokens of the user are revoked in the same transaction, the gateway sessions end on their next refresh.
+func (uc *AuthUsecase) ResetPassword(ctx context.Context, userId uuid.UUID, newPassword string) (statusCode int32, err error) {
+	user, err := uc.store.GetUserById(ctx, pgtype.UUID{Bytes: userId, Valid: true})
+	if err != nil {
+		return database.ErrorCode(err), err
 	}
-	err = uc.store.ChangePassword(ctx, changePassArgs)
+	// аккаунт мог быть захвачен: выданные до сброса refresh токены отзываются вместе со сменой пароля
+	return uc.setPassword(ctx, user, newPassword, time.Now())
+}
+
+// setPassword stores the new password, the refresh tokens issued before revokeTokensBefore are revoked, zero keeps them
+func (uc *AuthUsecase) setPassword(
+	ctx context.Context, user db.User, newPassword string, revokeTokensBefore time.Time) (statusCode int32, err error) {
+	err = uc.passwordPolicy.Validate(newPassword, user.Email, user.FirstName.String, user.LastName.String)
+	if err != nil {
+		return uc.passwordPolicy.GetErrorCode(err), err
+	}
+	statusCode, err = uc.checkPasswordHistory(ctx, user, newPassword)
+	if err != nil {
+		return statusCode, err
+	}
+	hashPass, err := uc.passwordHasher.HashPassword(newPassword)
+	if err != nil {
+		return server.PASSWORD_HASHING_ERR_CODE, err
+	}
+
+	changePassArgs := &db.ChangePasswordTxParams{
+		UserID:      user.ID,
+		Password:    hashPass,
+		HistorySize: int32(uc.passwordPolicy.HistorySize),
+
+		RevokeTokensBefore: revokeTokensBefore,
+	}
+	err = uc.store.TxChangePassword(ctx, changePassArgs)
+	if err != nil {
+		return database.ErrorCode(err), err
+	}
+	return server.SUCCESS_CODE, nil
+}
+
+func (uc *AuthUsecase) checkPasswordHistory(ctx context.Context, user db.User, password string) (int32, error) {
+	if uc.passwordPolicy.HistorySize <= 0 {
+		return server.SUCCESS_CODE, nil
+	}
+	// текущий пароль тоже считается использованным
+	if uc.passwordHasher.ComparePassword(user.Password, password) == nil {
+		return server.PASSWORD_REUSED_ERR_CODE, password_policy.ErrPasswordReused
+	}
+	historyArgs := db.GetPasswordHistoryByUserIdParams{
+		UserID: user.ID,
+		Limit:  int32(uc.passwordPolicy.HistorySize),
+	}
+	history, err := uc.store.GetPasswordHistoryByUserId(ctx, historyArgs)
 	if err != nil {
 		return database.ErrorCode(err), err
 	}
+	for _, entry := range history {
+		if uc.passwordHasher.ComparePassword(entry.Password, password) == nil {
+			return server.PASSWORD_REUSED_ERR_CODE, password_policy.ErrPasswordReused
+		}
+	}
 	return server.SUCCESS_CODE, nil
 }
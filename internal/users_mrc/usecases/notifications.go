@@ -0,0 +1,128 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "job_search_platform/internal/users_mrc/db/sqlc"
+	"job_search_platform/internal/users_mrc/entities"
+	"job_search_platform/pkg/database"
+	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/notifications"
+)
+
+var ErrMandatoryNotification = errors.New("security notifications cannot be disabled")
+
+type NotificationsUsecase struct {
+	store             db.Store
+	unsubscribeSigner *notifications.UnsubscribeSigner
+}
+
+func NewNotificationsUsecase(store db.Store, unsubscribeSigner *notifications.UnsubscribeSigner) NotificationsUsecase {
+	return NotificationsUsecase{store: store, unsubscribeSigner: unsubscribeSigner}
+}
+
+// GetPreferences returns every channel/category pair, the ones that were never saved get the default value
+func (uc *NotificationsUsecase) GetPreferences(
+	ctx context.Context, userId uuid.UUID) (preferences []entities.NotificationPreference, statusCode int32, err error) {
+	stored, err := uc.store.GetNotificationPreferencesByUserId(ctx, pgtype.UUID{Bytes: userId, Valid: true})
+	if err != nil {
+		return preferences, database.ErrorCode(err), err
+	}
+	saved := make(map[[2]string]bool, len(stored))
+	for _, pref := range stored {
+		saved[[2]string{string(pref.Channel), string(pref.Category)}] = pref.Enabled
+	}
+
+	for _, channel := range notifications.Channels {
+		for _, category := range notifications.Categories {
+			enabled, ok := saved[[2]string{channel, category}]
+			if !ok {
+				enabled = notifications.DefaultEnabled(channel, category)
+			}
+			if notifications.IsMandatory(category) {
+				enabled = true
+			}
+			preferences = append(preferences, entities.NotificationPreference{
+				Channel:  channel,
+				Category: category,
+				Enabled:  enabled,
+			})
+		}
+	}
+	return preferences, server.SUCCESS_CODE, nil
+}
+
+func (uc *NotificationsUsecase) UpdatePreferences(
+	ctx context.Context, userId uuid.UUID, payload entities.NotificationPreferencesUpdate) (statusCode int32, err error) {
+	args := make([]db.UpsertNotificationPreferenceParams, 0, len(payload.Preferences))
+	for _, pref := range payload.Preferences {
+		if notifications.IsMandatory(pref.Category) && !pref.Enabled {
+			return server.NOTIFICATION_MANDATORY_ERR_CODE, ErrMandatoryNotification
+		}
+		args = append(args, db.UpsertNotificationPreferenceParams{
+			UserID:   pgtype.UUID{Bytes: userId, Valid: true},
+			Channel:  db.NotificationChannels(pref.Channel),
+			Category: db.NotificationCategories(pref.Category),
+			Enabled:  pref.Enabled,
+		})
+	}
+	err = uc.store.TxUpdateNotificationPreferences(ctx, args)
+	if err != nil {
+		return database.ErrorCode(err), err
+	}
+	return server.SUCCESS_CODE, nil
+}
+
+// GetUnsubscribeInfo checks the link without changing anything, it is used to render the confirmation page
+func (uc *NotificationsUsecase) GetUnsubscribeInfo(token string) (info entities.UnsubscribeInfo, statusCode int32, err error) {
+	claims, err := uc.unsubscribeSigner.VerifyToken(token)
+	if err != nil {
+		return info, server.UNSUBSCRIBE_TOKEN_ERR_CODE, err
+	}
+	info = entities.UnsubscribeInfo{Email: claims.Email, Channel: claims.Channel, Category: claims.Category}
+	return info, server.SUCCESS_CODE, nil
+}
+
+func (uc *NotificationsUsecase) Unsubscribe(ctx context.Context, token string) (statusCode int32, err error) {
+	claims, err := uc.unsubscribeSigner.VerifyToken(token)
+	if err != nil {
+		return server.UNSUBSCRIBE_TOKEN_ERR_CODE, err
+	}
+	user, err := uc.store.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return database.ErrorCode(err), err
+	}
+	upsertArgs := db.UpsertNotificationPreferenceParams{
+		UserID:   user.ID,
+		Channel:  db.NotificationChannels(claims.Channel),
+		Category: db.NotificationCategories(claims.Category),
+		Enabled:  false,
+	}
+	_, err = uc.store.UpsertNotificationPreference(ctx, upsertArgs)
+	if err != nil {
+		return database.ErrorCode(err), err
+	}
+	return server.SUCCESS_CODE, nil
+}
+
+// IsEnabled implements notifications.PreferencesChecker for the task processor
+func (uc *NotificationsUsecase) IsEnabled(ctx context.Context, email string, channel string, category string) (bool, error) {
+	if notifications.IsMandatory(category) {
+		return true, nil
+	}
+	prefArgs := db.GetNotificationPreferenceByEmailParams{
+		Email:    email,
+		Channel:  db.NotificationChannels(channel),
+		Category: db.NotificationCategories(category),
+	}
+	enabled, err := uc.store.GetNotificationPreferenceByEmail(ctx, prefArgs)
+	if errors.Is(err, database.ErrRecordNotFound) {
+		return notifications.DefaultEnabled(channel, category), nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
@@ -42,10 +42,12 @@ func (usecase *UsersUsecase) GetUserDetail(
 }
 
 func (usecase *UsersUsecase) UpdateUser(ctx context.Context, payload entities.UserUpdate, userId uuid.UUID) (statusCode int32, err error) {
-	if payload.FirstName != "" || payload.LastName != "" {
+	if payload.FirstName != "" || payload.LastName != "" || payload.LangCode != "" {
 		updateUserParams := db.UpdateUserByIdParams{
+			ID:        pgtype.UUID{Bytes: userId, Valid: true},
 			FirstName: pgtype.Text{String: payload.FirstName, Valid: payload.FirstName != ""},
 			LastName:  pgtype.Text{String: payload.LastName, Valid: payload.LastName != ""},
+			LangCode:  pgtype.Text{String: payload.LangCode, Valid: payload.LangCode != ""},
 		}
 		_, err = usecase.store.UpdateUserById(ctx, updateUserParams)
 		if err != nil {
@@ -61,3 +63,16 @@ func (usecase *UsersUsecase) UpdateUser(ctx context.Context, payload entities.Us
 	}
 	return server.SUCCESS_CODE, nil
 }
+
+// BanUser blocks the sign-in and the token refresh of the user, the issued access tokens live until they expire
+func (usecase *UsersUsecase) BanUser(ctx context.Context, email string, banned bool) (user db.User, statusCode int32, err error) {
+	banArgs := db.BanUserByEmailParams{
+		Email:    email,
+		IsBanned: pgtype.Bool{Bool: banned, Valid: true},
+	}
+	user, err = usecase.store.BanUserByEmail(ctx, banArgs)
+	if err != nil {
+		return user, database.ErrorCode(err), err
+	}
+	return user, server.SUCCESS_CODE, nil
+}
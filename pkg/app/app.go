@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Signals end the application, the first one starts the graceful shutdown
+var Signals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+}
+
+// Hook is a component of the application. Every function is optional:
+// OnStart prepares the component and must not block, OnRun blocks while the component works
+// and its error is fatal, OnStop releases the component and is bounded by StopTimeout.
+type Hook struct {
+	Name        string
+	OnStart     func(ctx context.Context) error
+	OnRun       func(ctx context.Context) error
+	OnStop      func(ctx context.Context) error
+	StopTimeout time.Duration
+}
+
+// App starts the components in the order they were added and stops them in the reverse order,
+// so the http server stops before the task processor and both before the pools they use
+type App struct {
+	name        string
+	hooks       []Hook
+	stopTimeout time.Duration
+	logger      zerolog.Logger
+}
+
+func New(name string, stopTimeout time.Duration, logger zerolog.Logger) *App {
+	if stopTimeout <= 0 {
+		stopTimeout = 10 * time.Second
+	}
+	return &App{name: name, stopTimeout: stopTimeout, logger: logger}
+}
+
+func (application *App) Add(hook Hook) {
+	application.hooks = append(application.hooks, hook)
+}
+
+// OnStop adds a component that only has to be released, e.g. a connection pool
+func (application *App) OnStop(name string, stop func(ctx context.Context) error) {
+	application.Add(Hook{Name: name, OnStop: stop})
+}
+
+// Run starts the components and waits for a signal, the cancellation of ctx or the first fatal error.
+// The returned error is the one that ended the application, stop errors are returned only after a clean exit.
+func (application *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, Signals...)
+	defer stop()
+
+	started := 0
+	for _, hook := range application.hooks {
+		if hook.OnStart != nil {
+			application.logger.Info().Str("component", hook.Name).Msg("starting")
+			if err := hook.OnStart(ctx); err != nil {
+				err = fmt.Errorf("cannot start %s: %w", hook.Name, err)
+				application.stop(started)
+				return err
+			}
+		}
+		started++
+	}
+
+	// ctx отменяется при остановке, иначе OnRun не узнает о ней
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	fatal := make(chan error, len(application.hooks))
+	for _, hook := range application.hooks {
+		if hook.OnRun == nil {
+			continue
+		}
+		go func(hook Hook) {
+			err := hook.OnRun(runCtx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				fatal <- fmt.Errorf("%s failed: %w", hook.Name, err)
+			}
+		}(hook)
+	}
+	application.logger.Info().Str("app", application.name).Msg("started")
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		application.logger.Info().Str("app", application.name).Msg("shutting down")
+	case runErr = <-fatal:
+		application.logger.Error().Err(runErr).Str("app", application.name).Msg("shutting down after a fatal error")
+	}
+	cancelRun()
+
+	stopErr := application.stop(started)
+	if runErr != nil {
+		return runErr
+	}
+	return stopErr
+}
+
+// stop releases the first count components in the reverse order, every one gets its own timeout
+func (application *App) stop(count int) error {
+	var firstErr error
+	for i := count - 1; i >= 0; i-- {
+		hook := application.hooks[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		timeout := hook.StopTimeout
+		if timeout <= 0 {
+			timeout = application.stopTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := hook.OnStop(ctx)
+		cancel()
+		if err != nil {
+			application.logger.Error().Err(err).Str("component", hook.Name).Msg("cannot stop")
+			if firstErr == nil {
+				firstErr = fmt.Errorf("cannot stop %s: %w", hook.Name, err)
+			}
+			continue
+		}
+		application.logger.Info().Str("component", hook.Name).Msg("stopped")
+	}
+	return firstErr
+}
+
+// Wait bounds a stop function that does not accept a context, e.g. the asynq shutdown
+func Wait(stop func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			stop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
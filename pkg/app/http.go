@@ -0,0 +1,22 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// HttpServer serves until the application stops, then waits for the active requests within the stop timeout
+func HttpServer(name string, server *http.Server) Hook {
+	return Hook{
+		Name: name,
+		OnRun: func(ctx context.Context) error {
+			err := server.ListenAndServe()
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		OnStop: server.Shutdown,
+	}
+}
@@ -0,0 +1,138 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind is the class of an error, it defines the http status
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooLarge
+	KindTooManyRequests
+	KindBadGateway
+	KindUnavailable
+	KindTimeout
+)
+
+var kinds = map[Kind]struct {
+	status int
+	name   string
+}{
+	KindInternal:        {http.StatusInternalServerError, "internal"},
+	KindInvalid:         {http.StatusBadRequest, "invalid"},
+	KindValidation:      {http.StatusBadRequest, "validation"},
+	KindUnauthorized:    {http.StatusUnauthorized, "unauthorized"},
+	KindForbidden:       {http.StatusForbidden, "forbidden"},
+	KindNotFound:        {http.StatusNotFound, "not_found"},
+	KindConflict:        {http.StatusConflict, "conflict"},
+	KindTooLarge:        {http.StatusRequestEntityTooLarge, "too_large"},
+	KindTooManyRequests: {http.StatusTooManyRequests, "too_many_requests"},
+	KindBadGateway:      {http.StatusBadGateway, "bad_gateway"},
+	KindUnavailable:     {http.StatusServiceUnavailable, "unavailable"},
+	KindTimeout:         {http.StatusGatewayTimeout, "timeout"},
+}
+
+func (kind Kind) Status() int {
+	return kinds[kind].status
+}
+
+func (kind Kind) String() string {
+	return kinds[kind].name
+}
+
+// FieldError is a problem with one field of the request. Code is the failed rule,
+// an empty Message is localized from validation.<code> when the response is written.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error. Code is a stable string for the clients, Legacy is the numeric code
+// of the old responses, the cause is logged and never sent to the client.
+type Error struct {
+	Kind   Kind
+	Code   string
+	Legacy int32
+	Fields []FieldError
+	cause  error
+}
+
+func New(kind Kind, code string, cause error) *Error {
+	return &Error{Kind: kind, Code: code, cause: cause}
+}
+
+func (err *Error) Error() string {
+	if err.cause != nil {
+		return err.Code + ": " + err.cause.Error()
+	}
+	return err.Code
+}
+
+func (err *Error) Unwrap() error {
+	return err.cause
+}
+
+// WithLegacy sets the numeric code kept in the responses for the old clients
+func (err *Error) WithLegacy(code int32) *Error {
+	err.Legacy = code
+	return err
+}
+
+func (err *Error) WithFields(fields ...FieldError) *Error {
+	err.Fields = append(err.Fields, fields...)
+	return err
+}
+
+func Internal(code string, cause error) *Error {
+	return New(KindInternal, code, cause)
+}
+
+func Invalid(code string, cause error) *Error {
+	return New(KindInvalid, code, cause)
+}
+
+func Validation(code string, fields ...FieldError) *Error {
+	return New(KindValidation, code, nil).WithFields(fields...)
+}
+
+func Unauthorized(code string, cause error) *Error {
+	return New(KindUnauthorized, code, cause)
+}
+
+func Forbidden(code string, cause error) *Error {
+	return New(KindForbidden, code, cause)
+}
+
+func NotFound(code string, cause error) *Error {
+	return New(KindNotFound, code, cause)
+}
+
+func Conflict(code string, cause error) *Error {
+	return New(KindConflict, code, cause)
+}
+
+// As returns the domain error in the chain, other errors become internal
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal("internal_error", err)
+}
+
+// Is reports whether the chain contains a domain error of the kind
+func Is(err error, kind Kind) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
@@ -0,0 +1,35 @@
+package apperr
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE classes that are caused by the request, not by the database
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+	invalidText         = "22P02"
+)
+
+// FromDatabase maps a pgx error: no rows is NotFound, constraint violations are Conflict or Invalid,
+// everything else is internal. legacy is the numeric code of the old responses.
+func FromDatabase(err error, legacy int32) *Error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return NotFound("record_not_found", err).WithLegacy(legacy)
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case uniqueViolation:
+			return Conflict("already_exists", err).WithLegacy(legacy)
+		case foreignKeyViolation:
+			return Conflict("related_record_conflict", err).WithLegacy(legacy)
+		case checkViolation, invalidText:
+			return Invalid("invalid_value", err).WithLegacy(legacy)
+		}
+	}
+	return Internal("database_error", err).WithLegacy(legacy)
+}
@@ -0,0 +1,101 @@
+package apperr
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"job_search_platform/pkg/i18n"
+	"net/http"
+	"strings"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body of an error response. Code, ErrorMessage and Error keep
+// the fields of the old response body, so existing clients go on reading them.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+
+	Code         int32       `json:"code"`
+	Error        string      `json:"error"`
+	ErrorMessage string      `json:"error_message"`
+	Body         interface{} `json:"body"`
+}
+
+// NewProblem builds the response body, the detail is localized by the request language
+func NewProblem(ctx *gin.Context, err *Error) Problem {
+	lang := i18n.Lang(ctx)
+	status := err.Kind.Status()
+	detail := i18n.T(lang, fmt.Sprintf("errors.%d", err.Legacy))
+	if err.Legacy == 0 || detail == fmt.Sprintf("errors.%d", err.Legacy) {
+		detail = i18n.T(lang, "problems."+err.Kind.String())
+	}
+	legacy := err.Legacy
+	if legacy == 0 {
+		legacy = unknownLegacyCode
+	}
+	return Problem{
+		Type:         "/problems/" + err.Code,
+		Title:        http.StatusText(status),
+		Status:       status,
+		Detail:       detail,
+		Instance:     ctx.Request.URL.Path,
+		Errors:       localizeFields(lang, err.Fields),
+		Code:         legacy,
+		Error:        err.Code,
+		ErrorMessage: detail,
+	}
+}
+
+// localizeFields fills the empty messages, the translation gets the rule parameter in place of %s
+func localizeFields(lang string, fields []FieldError) []FieldError {
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make([]FieldError, len(fields))
+	for i, field := range fields {
+		if field.Message == "" {
+			key := "validation." + field.Code
+			message := i18n.T(lang, key)
+			if message == key {
+				message = i18n.T(lang, "validation.invalid")
+			}
+			if strings.Contains(message, "%s") {
+				message = fmt.Sprintf(message, field.Param)
+			}
+			field.Message = message
+		}
+		result[i] = field
+	}
+	return result
+}
+
+// unknownLegacyCode is UNKNOWN_ERROR_CODE of the old responses
+const unknownLegacyCode int32 = 1
+
+// Write sends err as application/problem+json. The cause is logged: server errors as errors,
+// client errors at debug level, it is never part of the response.
+func Write(ctx *gin.Context, err error) {
+	appErr := As(err)
+	status := appErr.Kind.Status()
+	event := log.Ctx(ctx).Debug()
+	if status >= http.StatusInternalServerError {
+		event = log.Ctx(ctx).Error()
+	}
+	event.Err(appErr.Unwrap()).Str("code", appErr.Code).Int32("legacy_code", appErr.Legacy).
+		Int("status", status).Msg("request failed")
+
+	ctx.Header("Content-Type", ProblemContentType)
+	ctx.JSON(status, NewProblem(ctx, appErr))
+}
+
+// Abort writes err and stops the handler chain
+func Abort(ctx *gin.Context, err error) {
+	Write(ctx, err)
+	ctx.Abort()
+}
@@ -1,101 +1,242 @@
 package config
 
 import (
-	"fmt"
-	"github.com/spf13/viper"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
-type Config struct {
-	UsersMrcUrl string
-
-	Environment string `mapstructure:"NODE_ENV"`
-	// db
-	PostgresDriver string `mapstructure:"POSTGRES_DRIVER"`
-	PostgresSource string
-	DbName         string
-	DbUser         string
-	DbPassword     string
-	DbHost         string
-	DbPort         string
-	DbSSLMode      string
-	MigrationURL   string
-
-	Port string `mapstructure:"PORT"`
-
-	Origin string
-
-	AccessTokenPrivateKey  string        `mapstructure:"ACCESS_TOKEN_PRIVATE_KEY"`
-	AccessTokenPublicKey   string        `mapstructure:"ACCESS_TOKEN_PUBLIC_KEY"`
-	RefreshTokenPrivateKey string        `mapstructure:"REFRESH_TOKEN_PRIVATE_KEY"`
-	RefreshTokenPublicKey  string        `mapstructure:"REFRESH_TOKEN_PUBLIC_KEY"`
-	AccessTokenExpiresIn   time.Duration `mapstructure:"ACCESS_TOKEN_EXPIRED_IN"`
-	RefreshTokenExpiresIn  time.Duration `mapstructure:"REFRESH_TOKEN_EXPIRED_IN"`
-	AccessTokenMaxAge      int           `mapstructure:"ACCESS_TOKEN_MAXAGE"`
-	RefreshTokenMaxAge     int           `mapstructure:"REFRESH_TOKEN_MAXAGE"`
-	SessionDuration        int           `mapstructure:"SESSION_DURATION"`
-
-	HTTPServerAddress string
-	HTTPClientAddress string `mapstructure:"HTTP_CLIENT_ADDRESS"`
-
-	TokenSymmetricKey string `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-
-	// SMTP
-	SMTPAuthAddress     string `mapstructure:"SMTP_AUTH_ADDRESS"`
-	SMTPServerAddress   string `mapstructure:"SMTP_SERVER_ADDRESS"`
-	EmailSenderName     string `mapstructure:"EMAIL_SENDER_NAME"`
-	EmailSenderAddress  string `mapstructure:"EMAIL_SENDER_ADDRESS"`
-	EmailSenderPassword string `mapstructure:"EMAIL_SENDER_PASSWORD"`
-
-	// Redis
-	RedisAddress string
-}
-
-func LoadConfig(path, serviceName string) (config Config, err error) {
-	serviceNameToUpper := strings.ToUpper(serviceName)
-	viper.AddConfigPath(path)
-	viper.SetConfigType("env")
-	viper.SetConfigName("app")
-
-	viper.AutomaticEnv()
-
-	err = viper.ReadInConfig()
-	if err != nil {
-		return
-	}
-	err = viper.Unmarshal(&config)
+// Field tags:
+//   - env is the variable name, prefix:"env,service" reads it as <NODE_ENV>_<SERVICE>_<NAME>
+//   - default is used when the variable is not set
+//   - validate is checked after loading, see Load
+//   - secret hides the value in `config print --redacted`
+//
+// Every variable can be read from a file instead: <NAME>_FILE=/run/secrets/name (Docker/K8s secrets).
+
+// Common is read by every service
+type Common struct {
+	Environment       string `env:"NODE_ENV" validate:"required"`
+	HTTPServerAddress string `env:"HTTP_SERVER_ADDRESS" prefix:"env,service" validate:"required"`
+	// MetricsAddress is the separate /metrics listener, empty turns it off
+	MetricsAddress string `env:"METRICS_ADDRESS" prefix:"env,service"`
+
+	TokenSymmetricKey     string        `env:"TOKEN_SYMMETRIC_KEY" secret:"true" validate:"required,min=32"`
+	AccessTokenExpiresIn  time.Duration `env:"ACCESS_TOKEN_EXPIRED_IN" default:"15m" validate:"gt=0"`
+	RefreshTokenExpiresIn time.Duration `env:"REFRESH_TOKEN_EXPIRED_IN" default:"720h" validate:"gt=0"`
+
+	// Signed identity headers from the gateway to the services, the secret is required when they are on
+	// and must differ from TOKEN_SYMMETRIC_KEY: the services verifying the headers do not get the JWT key
+	GatewayIdentityHeaders bool          `env:"GATEWAY_IDENTITY_HEADERS" default:"false"`
+	GatewayIdentitySecret  string        `env:"GATEWAY_IDENTITY_SECRET" secret:"true" validate:"required_if=GatewayIdentityHeaders true,omitempty,min=32,nefield=TokenSymmetricKey"`
+	GatewayIdentityMaxAge  time.Duration `env:"GATEWAY_IDENTITY_MAX_AGE" default:"30s" validate:"gt=0"`
+
+	Logging
+	Tracing
+	Lifecycle
+
+	source *source
+}
+
+// Logging, LOG_FILE is a path, stdout or stderr, empty - stderr in DEV and ./logs/app.log otherwise
+type Logging struct {
+	LogLevel      string `env:"LOG_LEVEL" default:"info" validate:"oneof=trace debug info warn error fatal panic disabled"`
+	LogFormat     string `env:"LOG_FORMAT" validate:"omitempty,oneof=json console"` // empty - console in DEV
+	LogFile       string `env:"LOG_FILE"`
+	LogMaxSizeMb  int    `env:"LOG_MAX_SIZE_MB" default:"100" validate:"gt=0"`
+	LogMaxBackups int    `env:"LOG_MAX_BACKUPS" default:"5" validate:"gte=0"`
+	LogMaxAgeDays int    `env:"LOG_MAX_AGE_DAYS" default:"30" validate:"gte=0"`
+	LogCompress   bool   `env:"LOG_COMPRESS" default:"true"`
+}
+
+// Tracing: none, otlp, stdout or file
+type Tracing struct {
+	TracingExporter     string  `env:"TRACING_EXPORTER" default:"none" validate:"oneof=none otlp stdout file"`
+	TracingOtlpEndpoint string  `env:"TRACING_OTLP_ENDPOINT" validate:"omitempty,url"` // empty - OTEL_EXPORTER_OTLP_* variables
+	TracingFile         string  `env:"TRACING_FILE" default:"./logs/traces.json"`
+	TracingSampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" default:"1.0" validate:"gte=0,lte=1"`
+}
+
+// Lifecycle: health checks and shutdown, /readyz answers 503 for SHUTDOWN_DRAIN_DELAY before the server stops
+type Lifecycle struct {
+	HealthCheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" default:"2s" validate:"gt=0"`
+	HealthCheckSmtp    bool          `env:"HEALTH_CHECK_SMTP" default:"false"`
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s" validate:"gte=0"`
+	// ShutdownTimeout bounds the stop of every component of the application
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"10s" validate:"gt=0"`
+}
 
-	nodeEnv := config.Environment
+type Postgres struct {
+	DbName       string `env:"POSTGRES_DB" prefix:"env,service" validate:"required"`
+	DbUser       string `env:"POSTGRES_USER" prefix:"env,service" validate:"required"`
+	DbPassword   string `env:"POSTGRES_PASSWORD" prefix:"env,service" secret:"true" validate:"required"`
+	DbHost       string `env:"POSTGRES_HOST" prefix:"env,service" validate:"required"`
+	DbPort       string `env:"POSTGRES_PORT" prefix:"env,service" default:"5432" validate:"required,numeric"`
+	DbSSLMode    string `env:"SSL_MODE" prefix:"env" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	MigrationURL string `env:"MIGRATION_URL" prefix:"service" validate:"required"`
+
+	// PostgresSource is built from the fields above
+	PostgresSource string `secret:"true"`
+}
+
+func (postgres Postgres) dataSourceName() string {
+	return "postgres://" + postgres.DbUser + ":" + postgres.DbPassword + "@" +
+		postgres.DbHost + ":" + postgres.DbPort + "/" + postgres.DbName + "?sslmode=" + postgres.DbSSLMode
+}
 
-	// services
-	//originUsersMrcAddress := viper.GetString(fmt.Sprintf("%s_%s_HTTP_SERVER_ADDRESS", nodeEnv, "USERS_MRC"))
-	//config.HttpUsersMrcAddress = addHttpPrefix(originUsersMrcAddress)
-	config.UsersMrcUrl = viper.GetString(fmt.Sprintf("%s_%s_URL", nodeEnv, "USERS_MRC"))
+type Redis struct {
+	RedisAddress string `env:"REDIS_ADDRESS" prefix:"env,service" validate:"required"`
+}
 
-	// redis
-	config.RedisAddress = viper.GetString(fmt.Sprintf("%s_%s_REDIS_ADDRESS", nodeEnv, serviceNameToUpper))
+// Mail is the sender of the users_mrc emails
+type Mail struct {
+	EmailSenderName     string `env:"EMAIL_SENDER_NAME" validate:"required"`
+	EmailSenderAddress  string `env:"EMAIL_SENDER_ADDRESS" validate:"required,email"`
+	EmailSenderPassword string `env:"EMAIL_SENDER_PASSWORD" secret:"true" validate:"required"`
+}
 
-	// postgres
-	config.DbName = viper.GetString(fmt.Sprintf("%s_%s_POSTGRES_DB", nodeEnv, serviceNameToUpper))
-	config.DbUser = viper.GetString(fmt.Sprintf("%s_%s_POSTGRES_USER", nodeEnv, serviceNameToUpper))
-	config.DbPassword = viper.GetString(fmt.Sprintf("%s_%s_POSTGRES_PASSWORD", nodeEnv, serviceNameToUpper))
-	config.DbHost = viper.GetString(fmt.Sprintf("%s_%s_POSTGRES_HOST", nodeEnv, serviceNameToUpper))
-	config.DbPort = viper.GetString(fmt.Sprintf("%s_%s_POSTGRES_PORT", nodeEnv, serviceNameToUpper))
-	config.DbSSLMode = viper.GetString(fmt.Sprintf("%s_SSL_MODE", nodeEnv))
+type PasswordPolicy struct {
+	PasswordMinLength          int    `env:"PASSWORD_MIN_LENGTH" default:"8" validate:"gte=1"`
+	PasswordMaxLength          int    `env:"PASSWORD_MAX_LENGTH" default:"72" validate:"gtefield=PasswordMinLength"`
+	PasswordRequireUpper       bool   `env:"PASSWORD_REQUIRE_UPPER" default:"true"`
+	PasswordRequireLower       bool   `env:"PASSWORD_REQUIRE_LOWER" default:"true"`
+	PasswordRequireDigit       bool   `env:"PASSWORD_REQUIRE_DIGIT" default:"true"`
+	PasswordRequireSpecial     bool   `env:"PASSWORD_REQUIRE_SPECIAL" default:"false"`
+	PasswordForbidPersonalInfo bool   `env:"PASSWORD_FORBID_PERSONAL_INFO" default:"true"`
+	PasswordCheckBreached      bool   `env:"PASSWORD_CHECK_BREACHED" default:"true"`
+	BreachedPasswordsFile      string `env:"BREACHED_PASSWORDS_FILE" validate:"omitempty,file"`
+	PasswordHistorySize        int    `env:"PASSWORD_HISTORY_SIZE" default:"0" validate:"gte=0"`
+}
 
-	config.MigrationURL = viper.GetString(fmt.Sprintf("%s_MIGRATION_URL", serviceNameToUpper))
+type PasswordHashing struct {
+	PasswordHashAlgorithm string `env:"PASSWORD_HASH_ALGORITHM" default:"argon2id" validate:"oneof=argon2id bcrypt"`
+	BcryptCost            int    `env:"BCRYPT_COST" default:"12" validate:"gte=4,lte=31"`
+	Argon2Memory          uint32 `env:"ARGON2_MEMORY" default:"65536" validate:"gte=8"` // KiB
+	Argon2Iterations      uint32 `env:"ARGON2_ITERATIONS" default:"3" validate:"gte=1"`
+	Argon2Parallelism     uint8  `env:"ARGON2_PARALLELISM" default:"2" validate:"gte=1"`
+	Argon2SaltLength      uint32 `env:"ARGON2_SALT_LENGTH" default:"16" validate:"gte=8"`
+	Argon2KeyLength       uint32 `env:"ARGON2_KEY_LENGTH" default:"32" validate:"gte=16"`
+}
 
-	// server
-	config.HTTPServerAddress = viper.GetString(fmt.Sprintf("%s_%s_HTTP_SERVER_ADDRESS", nodeEnv, serviceNameToUpper))
-	config.Origin = viper.GetString(fmt.Sprintf("%s_ORIGIN", nodeEnv))
-	config.PostgresSource = fmt.Sprintf(
-		"%s://%s:%s@%s:%s/%s?sslmode=%s", "postgres", config.DbUser, config.DbPassword,
-		config.DbHost, config.DbPort, config.DbName, config.DbSSLMode)
+type MagicLink struct {
+	MagicLinkExpiresIn   time.Duration `env:"MAGIC_LINK_EXPIRED_IN" default:"15m" validate:"gt=0"`
+	MagicLinkBindSession bool          `env:"MAGIC_LINK_BIND_SESSION" default:"true"`
+	MagicLinkPath        string        `env:"MAGIC_LINK_PATH" default:"/auth/magic-link" validate:"startswith=/"`
+}
 
+// Sessions of the gateway, the cookie max-age is SESSION_DURATION
+type Sessions struct {
+	SessionDuration        int           `env:"SESSION_DURATION" default:"30" validate:"gt=0"` // days
+	SessionStore           string        `env:"SESSION_STORE" default:"redis" validate:"oneof=redis postgres"`
+	SessionTouchInterval   time.Duration `env:"SESSION_TOUCH_INTERVAL" default:"1m" validate:"gte=0"`
+	SessionCleanupInterval time.Duration `env:"SESSION_CLEANUP_INTERVAL" default:"10m" validate:"gt=0"`
+	SessionRetention       time.Duration `env:"SESSION_RETENTION" default:"720h" validate:"gte=0"` // how long ended sessions are kept for the statistics
+
+	SessionCookieName       string `env:"SESSION_COOKIE_NAME" default:"session_id" validate:"required"`
+	SessionCookieDomain     string `env:"SESSION_COOKIE_DOMAIN"` // empty - host only
+	SessionCookieSecure     bool   `env:"SESSION_COOKIE_SECURE" default:"true"`
+	SessionCookieHttpOnly   bool   `env:"SESSION_COOKIE_HTTP_ONLY" default:"true"`
+	SessionCookieSameSite   string `env:"SESSION_COOKIE_SAME_SITE" default:"lax" validate:"oneof=lax strict none"`
+	SessionCookieHostPrefix bool   `env:"SESSION_COOKIE_HOST_PREFIX" default:"false"`
+}
+
+type Gateway struct {
+	Origin string `env:"ORIGIN" prefix:"env" validate:"required"`
+
+	GatewayRoutesFile  string `env:"GATEWAY_ROUTES_FILE"` // empty - the table bundled into the binary
+	GatewayRoutesWatch bool   `env:"GATEWAY_ROUTES_WATCH" default:"true"`
+	GatewayAdminRole   string `env:"GATEWAY_ADMIN_ROLE" default:"administrators" validate:"required"`
+	// GatewayTrustedProxies are the IPs or CIDRs of the load balancers, comma separated. Only they may set
+	// X-Forwarded-For and X-Real-IP, empty - the client IP is the address of the connection.
+	GatewayTrustedProxies string `env:"GATEWAY_TRUSTED_PROXIES"`
+
+	// Proxy transport
+	ProxyMaxIdleConns        int           `env:"PROXY_MAX_IDLE_CONNS" default:"256" validate:"gte=0"`
+	ProxyMaxIdleConnsPerHost int           `env:"PROXY_MAX_IDLE_CONNS_PER_HOST" default:"64" validate:"gte=0"`
+	ProxyIdleConnTimeout     time.Duration `env:"PROXY_IDLE_CONN_TIMEOUT" default:"90s" validate:"gte=0"`
+	ProxyDialTimeout         time.Duration `env:"PROXY_DIAL_TIMEOUT" default:"5s" validate:"gt=0"`
+	ProxyMaxRetries          int           `env:"PROXY_MAX_RETRIES" default:"2" validate:"gte=0"`
+	ProxyRetryBackoff        time.Duration `env:"PROXY_RETRY_BACKOFF" default:"50ms" validate:"gte=0"`
+
+	// Circuit breaker
+	BreakerFailureThreshold int           `env:"BREAKER_FAILURE_THRESHOLD" default:"5" validate:"gte=1"`
+	BreakerOpenTimeout      time.Duration `env:"BREAKER_OPEN_TIMEOUT" default:"30s" validate:"gt=0"`
+	BreakerHalfOpenRequests int           `env:"BREAKER_HALF_OPEN_REQUESTS" default:"1" validate:"gte=1"`
+
+	// Access token refresh
+	TokenRefreshTimeout time.Duration `env:"TOKEN_REFRESH_TIMEOUT" default:"5s" validate:"gt=0"`
+	TokenRefreshBefore  time.Duration `env:"TOKEN_REFRESH_BEFORE" default:"30s" validate:"gte=0"` // refresh proactively when the token expires sooner
+
+	// Rate limiting, the limits are in the route table
+	RateLimitEnabled bool `env:"RATE_LIMIT_ENABLED" default:"true"`
+
+	// GatewayDocsEnabled serves Swagger UI at /docs with the specifications of the upstreams, empty - on only in DEV
+	GatewayDocsEnabled  bool          `env:"GATEWAY_DOCS_ENABLED"`
+	GatewayDocsCacheTTL time.Duration `env:"GATEWAY_DOCS_CACHE_TTL" default:"1m" validate:"gt=0"` // how long the aggregated specification is served without asking the upstreams
+}
+
+// UsersMrc is the configuration of the users service and its task processor
+type UsersMrc struct {
+	Common
+	Postgres
+	Redis
+	Mail
+	PasswordPolicy
+	PasswordHashing
+	MagicLink
+
+	// HTTPClientAddress is the frontend, links in the emails point to it
+	HTTPClientAddress string `env:"HTTP_CLIENT_ADDRESS" validate:"required,url"`
+	// UnsubscribeSecretKey signs the links in the emails, it must differ from TOKEN_SYMMETRIC_KEY
+	UnsubscribeSecretKey string `env:"UNSUBSCRIBE_SECRET_KEY" secret:"true" validate:"required,min=32,nefield=TokenSymmetricKey"`
+}
+
+// GatewayMrc is the configuration of the api gateway
+type GatewayMrc struct {
+	Common
+	Postgres
+	Redis
+	Sessions
+	Gateway
+}
+
+func LoadUsersMrc(path string) (config UsersMrc, err error) {
+	err = Load(path, "users_mrc", &config, func() {
+		config.PostgresSource = config.Postgres.dataSourceName()
+	})
 	return
 }
 
-func addHttpPrefix(address string) string {
-	return "http://" + address
+func LoadGatewayMrc(path string) (config GatewayMrc, err error) {
+	err = Load(path, "gateway_mrc", &config, func() {
+		config.PostgresSource = config.Postgres.dataSourceName()
+		if config.GatewayRoutesFile != "" && !filepath.IsAbs(config.GatewayRoutesFile) {
+			config.GatewayRoutesFile = filepath.Join(path, config.GatewayRoutesFile)
+		}
+		if raw, _ := config.source.get("GATEWAY_DOCS_ENABLED"); raw == "" {
+			config.GatewayDocsEnabled = config.Environment == "DEV"
+		}
+	})
+	return
+}
+
+// TrustedProxies splits GATEWAY_TRUSTED_PROXIES, nil when it is empty
+func (gateway Gateway) TrustedProxies() []string {
+	var result []string
+	for _, proxy := range strings.Split(gateway.GatewayTrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			result = append(result, proxy)
+		}
+	}
+	return result
+}
+
+// ServiceUrl returns the address of a backend service from <NODE_ENV>_<SERVICE>_URL,
+// several instances are separated by commas
+func (config GatewayMrc) ServiceUrl(serviceName string) string {
+	if config.source == nil {
+		return ""
+	}
+	value, _ := config.source.get(config.Environment + "_" + strings.ToUpper(serviceName) + "_URL")
+	return value
 }
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error lists every invalid or missing variable, so one start shows all of them
+type Error struct {
+	Service  string
+	Problems []string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("invalid %s configuration:\n  %s", err.Service, strings.Join(err.Problems, "\n  "))
+}
+
+// source reads the variables from the environment and app.env, the environment wins
+type source struct {
+	viper       *viper.Viper
+	service     string
+	environment string
+}
+
+func newSource(path, service string) (*source, error) {
+	v := viper.New()
+	v.AddConfigPath(path)
+	v.SetConfigType("env")
+	v.SetConfigName("app")
+	v.AutomaticEnv()
+	// app.env не обязателен, в контейнерах все приходит из окружения
+	err := v.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return nil, err
+	}
+	return &source{viper: v, service: service}, nil
+}
+
+// get returns the variable or the content of the file from <KEY>_FILE
+func (source *source) get(key string) (string, error) {
+	if path := source.viper.GetString(key + "_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot read %s_FILE: %w", key, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	}
+	return source.viper.GetString(key), nil
+}
+
+// key builds the variable name from the env and prefix tags
+func (source *source) key(tag reflect.StructTag) string {
+	var parts []string
+	for _, prefix := range strings.Split(tag.Get("prefix"), ",") {
+		switch prefix {
+		case "env":
+			parts = append(parts, source.environment)
+		case "service":
+			parts = append(parts, strings.ToUpper(source.service))
+		}
+	}
+	return strings.Join(append(parts, tag.Get("env")), "_")
+}
+
+type field struct {
+	name   string // env tag
+	key    string // variable name with the prefixes
+	goName string // struct field, the cross-field rules refer to it
+	tag    reflect.StructTag
+	value  reflect.Value
+	secret bool
+}
+
+// fields walks the struct and the embedded sections, fields without the env tag are derived
+func (source *source) fields(value reflect.Value) []field {
+	var result []field
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		structField := valueType.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		if structField.Anonymous && structField.Type.Kind() == reflect.Struct {
+			result = append(result, source.fields(value.Field(i))...)
+			continue
+		}
+		name := structField.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		result = append(result, field{
+			name:   name,
+			key:    source.key(structField.Tag),
+			goName: structField.Name,
+			tag:    structField.Tag,
+			value:  value.Field(i),
+			secret: structField.Tag.Get("secret") == "true",
+		})
+	}
+	return result
+}
+
+type sourced interface {
+	setSource(source *source)
+	getSource() *source
+}
+
+func (common *Common) setSource(source *source) {
+	common.source = source
+}
+
+func (common *Common) getSource() *source {
+	return common.source
+}
+
+// Load fills the service config from the variables, complete sets the derived fields before the validation.
+// All problems are collected into *Error, the config is filled as far as possible anyway.
+func Load(path, service string, config sourced, complete func()) error {
+	source, err := newSource(path, service)
+	if err != nil {
+		return fmt.Errorf("cannot read config: %w", err)
+	}
+	environment, err := source.get("NODE_ENV")
+	if err != nil {
+		return err
+	}
+	source.environment = environment
+	config.setSource(source)
+
+	var problems []string
+	// поля, которые не удалось прочитать, не проверяются повторно
+	failed := make(map[string]bool)
+	fields := source.fields(reflect.ValueOf(config).Elem())
+	for _, field := range fields {
+		raw, err := source.get(field.key)
+		if err != nil {
+			problems = append(problems, err.Error())
+			failed[field.name] = true
+			continue
+		}
+		if raw == "" {
+			raw = field.tag.Get("default")
+		}
+		if raw == "" {
+			continue
+		}
+		if err = setValue(field.value, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s", field.key, err))
+			failed[field.name] = true
+		}
+	}
+	complete()
+
+	problems = append(problems, validate(config, fields, failed)...)
+	if len(problems) > 0 {
+		return &Error{Service: service, Problems: problems}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("is not a duration: %q", raw)
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("is not a boolean: %q", raw)
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not an integer: %q", raw)
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a positive integer: %q", raw)
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a number: %q", raw)
+		}
+		value.SetFloat(parsed)
+	default:
+		return fmt.Errorf("has unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// validate checks the validate tags and names the variables in the problems
+func validate(config interface{}, fields []field, failed map[string]bool) []string {
+	byName := make(map[string]field, len(fields))
+	keys := make(map[string]string, len(fields))
+	for _, field := range fields {
+		byName[field.name] = field
+		keys[field.goName] = field.key
+	}
+	checker := validator.New(validator.WithRequiredStructEnabled())
+	checker.RegisterTagNameFunc(func(structField reflect.StructField) string {
+		if name := structField.Tag.Get("env"); name != "" {
+			return name
+		}
+		return structField.Name
+	})
+
+	err := checker.Struct(config)
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		if err != nil {
+			return []string{err.Error()}
+		}
+		return nil
+	}
+	problems := make([]string, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		if failed[fieldError.Field()] {
+			continue
+		}
+		field, ok := byName[fieldError.Field()]
+		key := fieldError.Field()
+		if ok {
+			key = field.key
+		}
+		problem := fmt.Sprintf("%s %s", key, describe(fieldError, keys))
+		if ok && !field.secret && fieldError.Tag() != "required" {
+			problem += fmt.Sprintf(", got %q", fmt.Sprint(fieldError.Value()))
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// describe explains the failed rule, keys name the variables of the fields in the cross-field rules
+func describe(fieldError validator.FieldError, keys map[string]string) string {
+	param := fieldError.Param()
+	switch fieldError.Tag() {
+	case "gtefield", "nefield", "required_if":
+		name, rest, _ := strings.Cut(param, " ")
+		if key, ok := keys[name]; ok {
+			param = strings.TrimSpace(key + " " + rest)
+		}
+	}
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + param
+	case "min":
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", param)
+		}
+		return "must be at least " + param
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be at least " + param
+	case "lte":
+		return "must be at most " + param
+	case "gtefield":
+		return "must not be less than " + param
+	case "nefield":
+		return "must differ from " + param
+	case "required_if":
+		return "is required when " + strings.Replace(param, " ", " is ", 1)
+	case "url":
+		return "must be a url with a scheme"
+	case "email":
+		return "must be an email address"
+	case "file":
+		return "must be an existing file"
+	case "numeric":
+		return "must be a number"
+	case "startswith":
+		return fmt.Sprintf("must start with %q", param)
+	}
+	if param != "" {
+		return fmt.Sprintf("failed the %s=%s check", fieldError.Tag(), param)
+	}
+	return fmt.Sprintf("failed the %s check", fieldError.Tag())
+}
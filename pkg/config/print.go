@@ -0,0 +1,51 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+const redactedValue = "******"
+
+// Print writes the loaded variables as KEY=value in the declaration order,
+// redacted hides the secrets so the output can be pasted into an issue
+func Print(w io.Writer, config sourced, redacted bool) {
+	source := config.getSource()
+	if source == nil {
+		return
+	}
+	for _, field := range source.fields(reflect.ValueOf(config).Elem()) {
+		value := fmt.Sprint(field.value.Interface())
+		if redacted && field.secret && value != "" {
+			value = redactedValue
+		}
+		fmt.Fprintf(w, "%s=%s\n", field.key, value)
+	}
+}
+
+// RunCommand handles `<binary> config print [--redacted]`. It returns false for other arguments,
+// otherwise the exit code: the config is printed even when it is invalid, the problems go to stderr.
+func RunCommand(args []string, config sourced, loadErr error) (int, bool) {
+	if len(args) < 1 || args[0] != "config" {
+		return 0, false
+	}
+	if len(args) < 2 || args[1] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: config print [--redacted]")
+		return 2, true
+	}
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	redacted := flags.Bool("redacted", false, "hide the secrets")
+	if err := flags.Parse(args[2:]); err != nil {
+		return 2, true
+	}
+
+	Print(os.Stdout, config, *redacted)
+	if loadErr != nil {
+		fmt.Fprintln(os.Stderr, loadErr)
+		return 1, true
+	}
+	return 0, true
+}
@@ -2,18 +2,114 @@ package database
 
 import (
 	"errors"
+	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"os"
 )
 
+// RunDBMigration migrates up, the services run it on start
 func RunDBMigration(migrationURL string, dbSource string) error {
-	migration, err := migrate.New(migrationURL, dbSource)
+	migrator, err := NewMigrator(migrationURL, dbSource)
 	if err != nil {
 		return err
 	}
-	if err = migration.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return err
+	defer migrator.Close()
+	return migrator.Up()
+}
+
+// Migrator runs the migrations of one service in both directions, platformctl uses it
+type Migrator struct {
+	migration    *migrate.Migrate
+	migrationURL string
+}
+
+func NewMigrator(migrationURL string, dbSource string) (*Migrator, error) {
+	migration, err := migrate.New(migrationURL, dbSource)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{migration: migration, migrationURL: migrationURL}, nil
+}
+
+// Up applies every pending migration
+func (migrator *Migrator) Up() error {
+	return ignoreNoChange(migrator.migration.Up())
+}
+
+// Down rolls back the last steps migrations
+func (migrator *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	return ignoreNoChange(migrator.migration.Steps(-steps))
+}
+
+// To migrates up or down to the version
+func (migrator *Migrator) To(version uint) error {
+	return ignoreNoChange(migrator.migration.Migrate(version))
+}
+
+// Force sets the version without running migrations, it clears the dirty state after a failed migration
+func (migrator *Migrator) Force(version int) error {
+	return migrator.migration.Force(version)
+}
+
+type MigrationStatus struct {
+	Version uint
+	Dirty   bool
+	// Latest is the last version of the migration files, Pending are the versions above Version
+	Latest  uint
+	Pending []uint
+}
+
+func (migrator *Migrator) Status() (status MigrationStatus, err error) {
+	status.Version, status.Dirty, err = migrator.migration.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, err
+	}
+	versions, err := migrationVersions(migrator.migrationURL)
+	if err != nil {
+		return status, err
+	}
+	for _, version := range versions {
+		status.Latest = version
+		if version > status.Version {
+			status.Pending = append(status.Pending, version)
+		}
+	}
+	return status, nil
+}
+
+func (migrator *Migrator) Close() error {
+	sourceErr, dbErr := migrator.migration.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// migrationVersions lists the versions of the migration files in ascending order
+func migrationVersions(migrationURL string) ([]uint, error) {
+	driver, err := source.Open(migrationURL)
+	if err != nil {
+		return nil, err
+	}
+	defer driver.Close()
+	var versions []uint
+	version, err := driver.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = driver.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
 	}
-	return nil
+	return err
 }
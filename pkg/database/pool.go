@@ -0,0 +1,17 @@
+package database
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"job_search_platform/pkg/tracing"
+)
+
+// NewPool connects to postgres with every query traced
+func NewPool(ctx context.Context, source string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(source)
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
@@ -1,10 +1,14 @@
 package common
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
 
 type CommonResponse struct {
-	Code  int    `json:"code"`
-	Error string `json:"error"`
+	Code         int    `json:"code"`
+	Error        string `json:"error"`
+	ErrorMessage string `json:"error_message"`
 }
 
 type UserResponse struct {
@@ -12,6 +16,7 @@ type UserResponse struct {
 	Email    string    `json:"email"`
 	Groups   []string  `json:"roles"`
 	UserType string    `json:"user_type"`
+	LangCode string    `json:"lang_code"`
 }
 
 type SignInBodyResponse struct {
@@ -33,10 +38,30 @@ type RefreshTokenResponse struct {
 	RefreshTokenBodyResponse `json:"body"`
 }
 
+// TaskMetadata is embedded in the task payloads and carries the trace context of the enqueuing request
+type TaskMetadata struct {
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+func (metadata *TaskMetadata) SetTraceContext(carrier map[string]string) {
+	metadata.TraceContext = carrier
+}
+
 type PayloadSendVerifyEmail struct {
+	TaskMetadata
 	Email     string `json:"email"`
 	JWTToken  string `json:"jwt_token"`
 	LangCode  string `json:"lang_code"`
 	LastName  string `json:"last_name"`
 	FirstName string `json:"first_name"`
 }
+
+type PayloadSendLoginLink struct {
+	TaskMetadata
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	LangCode  string    `json:"lang_code"`
+	LastName  string    `json:"last_name"`
+	FirstName string    `json:"first_name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
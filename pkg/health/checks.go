@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// PgxPool pings postgres through the pool, so an exhausted pool fails the check too
+func PgxPool(pool *pgxpool.Pool) CheckFunc {
+	return func(ctx context.Context) error {
+		return pool.Ping(ctx)
+	}
+}
+
+func Redis(client redis.UniversalClient) CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// Asynq pings the redis used by the task queues
+func Asynq(redisOpt asynq.RedisClientOpt) CheckFunc {
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisOpt.Addr,
+		Username: redisOpt.Username,
+		Password: redisOpt.Password,
+		DB:       redisOpt.DB,
+	})
+	return Redis(client)
+}
+
+// Tcp only opens a connection, e.g. to the SMTP server, without speaking the protocol
+func Tcp(address string) CheckFunc {
+	return func(ctx context.Context) error {
+		if address == "" {
+			return errors.New("address is not configured")
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// Http expects a 2xx answer from the url, e.g. the /healthz of an upstream service
+func Http(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %d", response.StatusCode)
+		}
+		return nil
+	}
+}
+
+// Any passes when one of the instances of a service passes
+func Any(instances func() []string, check func(instance string) CheckFunc) CheckFunc {
+	return func(ctx context.Context) error {
+		var errs []string
+		for _, instance := range instances() {
+			err := check(instance)(ctx)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, fmt.Sprintf("%s: %s", instance, err))
+		}
+		if len(errs) == 0 {
+			return errors.New("no instances")
+		}
+		return errors.New(strings.Join(errs, "; "))
+	}
+}
@@ -0,0 +1,147 @@
+package health
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDraining = "draining"
+)
+
+// CheckFunc returns an error when the dependency is not usable
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	optional bool
+}
+
+// Result is the status of a single dependency in the /readyz response, Error is only logged
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Optional  bool    `json:"optional,omitempty"`
+	Error     string  `json:"-"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Health runs the readiness checks of a service. Liveness only tells that the process answers,
+// readiness fails when a required dependency is down or the service is shutting down.
+type Health struct {
+	timeout  time.Duration
+	checks   []check
+	draining atomic.Bool
+}
+
+func New(timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Health{timeout: timeout}
+}
+
+// Add registers a required dependency, readiness fails when it is down
+func (health *Health) Add(name string, fn CheckFunc) {
+	health.checks = append(health.checks, check{name: name, fn: fn})
+}
+
+// AddOptional registers a dependency that is reported but does not fail readiness
+func (health *Health) AddOptional(name string, fn CheckFunc) {
+	health.checks = append(health.checks, check{name: name, fn: fn, optional: true})
+}
+
+// SetDraining turns readiness off, load balancers stop sending new requests before the server is stopped
+func (health *Health) SetDraining() {
+	health.draining.Store(true)
+}
+
+func (health *Health) Draining() bool {
+	return health.draining.Load()
+}
+
+// Check runs all checks concurrently, each one is limited by the health timeout
+func (health *Health) Check(ctx context.Context) (Report, bool) {
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(health.checks))}
+	ready := true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range health.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, health.timeout)
+			defer cancel()
+			start := time.Now()
+			err := c.fn(checkCtx)
+			result := Result{
+				Status:    StatusUp,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				Optional:  c.optional,
+			}
+			if err != nil {
+				result.Status = StatusDown
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if err != nil && !c.optional {
+				ready = false
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if !ready {
+		report.Status = StatusDown
+	}
+	if health.Draining() {
+		report.Status = StatusDraining
+		ready = false
+	}
+	return report, ready
+}
+
+// Liveness answers while the process is able to serve http, dependencies are not checked
+func (health *Health) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": StatusUp})
+}
+
+// Readiness answers 503 when a required dependency is down or the service is draining.
+// /readyz is reachable from the public port, so the errors of the failed checks go to the log
+// and the response reports only the status and latency of each dependency.
+func (health *Health) Readiness(ctx *gin.Context) {
+	report, ready := health.Check(ctx.Request.Context())
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	for name, result := range report.Checks {
+		if result.Status == StatusDown {
+			log.Ctx(ctx).Warn().Str("check", name).Bool("optional", result.Optional).
+				Str("error", result.Error).Msg("readiness check failed")
+		}
+	}
+	ctx.JSON(status, report)
+}
+
+// Register adds /healthz and /readyz to the router
+func (health *Health) Register(router gin.IRoutes) {
+	router.GET("/healthz", health.Liveness)
+	router.GET("/readyz", health.Readiness)
+}
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idHasher encodes hashes in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2idParams struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (hasher *Argon2idHasher) HashPassword(password string) (string, error) {
+	salt := make([]byte, hasher.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("cannot generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, hasher.Iterations, hasher.Memory, hasher.Parallelism, hasher.KeyLength)
+
+	encoding := base64.RawStdEncoding
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, hasher.Memory, hasher.Iterations, hasher.Parallelism,
+		encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+func (hasher *Argon2idHasher) ComparePassword(hashedPassword string, candidatePassword string) error {
+	params, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(candidatePassword), params.salt, params.iterations, params.memory,
+		params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+func (hasher *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return params.version != argon2.Version ||
+		params.memory != hasher.Memory ||
+		params.iterations != hasher.Iterations ||
+		params.parallelism != hasher.Parallelism ||
+		uint32(len(params.salt)) != hasher.SaltLength ||
+		uint32(len(params.key)) != hasher.KeyLength
+}
+
+func (hasher *Argon2idHasher) Matches(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, argon2idPrefix)
+}
+
+func decodeArgon2id(hashedPassword string) (*argon2idParams, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownHashFormat
+	}
+
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &params.version); err != nil {
+		return nil, ErrUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, ErrUnknownHashFormat
+	}
+
+	var err error
+	encoding := base64.RawStdEncoding
+	if params.salt, err = encoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownHashFormat
+	}
+	if params.key, err = encoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, ErrUnknownHashFormat
+	}
+	return params, nil
+}
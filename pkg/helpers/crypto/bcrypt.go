@@ -0,0 +1,49 @@
+package crypto
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// BcryptHasher is kept to verify the hashes created before argon2id became the default
+type BcryptHasher struct {
+	Cost int
+}
+
+func (hasher *BcryptHasher) HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost())
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+func (hasher *BcryptHasher) ComparePassword(hashedPassword string, candidatePassword string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(candidatePassword))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedPassword
+	}
+	return err
+}
+
+func (hasher *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	if err != nil {
+		return true
+	}
+	return cost < hasher.cost()
+}
+
+func (hasher *BcryptHasher) Matches(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+func (hasher *BcryptHasher) cost() int {
+	if hasher.Cost < bcrypt.MinCost {
+		return bcrypt.DefaultCost
+	}
+	return hasher.Cost
+}
@@ -1,12 +1,81 @@
 package crypto
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"fmt"
+	"job_search_platform/pkg/config"
+	"strings"
+)
 
-func HashPassword(password string) string {
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashedPassword)
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatchedPassword = errors.New("hashed password does not match the given password")
+	ErrUnknownHashFormat  = errors.New("unknown password hash format")
+)
+
+// PasswordHasher hashes new passwords and verifies stored hashes
+type PasswordHasher interface {
+	HashPassword(password string) (string, error)
+	ComparePassword(hashedPassword string, candidatePassword string) error
+	// NeedsRehash reports whether the stored hash was made with another algorithm or outdated parameters
+	NeedsRehash(hashedPassword string) bool
+}
+
+// algorithmHasher is a single hash algorithm that recognizes its own encoded hashes
+type algorithmHasher interface {
+	PasswordHasher
+	Matches(hashedPassword string) bool
+}
+
+// VersionedHasher hashes with the configured algorithm and verifies hashes made by any supported one,
+// the algorithm and its parameters are taken from the encoded hash itself.
+type VersionedHasher struct {
+	current    algorithmHasher
+	algorithms []algorithmHasher
+}
+
+func NewPasswordHasher(config config.PasswordHashing) (PasswordHasher, error) {
+	argon2id := &Argon2idHasher{
+		Memory:      config.Argon2Memory,
+		Iterations:  config.Argon2Iterations,
+		Parallelism: config.Argon2Parallelism,
+		SaltLength:  config.Argon2SaltLength,
+		KeyLength:   config.Argon2KeyLength,
+	}
+	bcryptHasher := &BcryptHasher{Cost: config.BcryptCost}
+
+	hasher := &VersionedHasher{algorithms: []algorithmHasher{argon2id, bcryptHasher}}
+	switch strings.ToLower(config.PasswordHashAlgorithm) {
+	case AlgorithmArgon2id, "":
+		hasher.current = argon2id
+	case AlgorithmBcrypt:
+		hasher.current = bcryptHasher
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", config.PasswordHashAlgorithm)
+	}
+	return hasher, nil
+}
+
+func (hasher *VersionedHasher) HashPassword(password string) (string, error) {
+	return hasher.current.HashPassword(password)
+}
+
+func (hasher *VersionedHasher) ComparePassword(hashedPassword string, candidatePassword string) error {
+	for _, algorithm := range hasher.algorithms {
+		if algorithm.Matches(hashedPassword) {
+			return algorithm.ComparePassword(hashedPassword, candidatePassword)
+		}
+	}
+	return ErrUnknownHashFormat
 }
 
-func ComparePassword(hashedPassword string, candidatePassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(candidatePassword))
+func (hasher *VersionedHasher) NeedsRehash(hashedPassword string) bool {
+	if !hasher.current.Matches(hashedPassword) {
+		return true
+	}
+	return hasher.current.NeedsRehash(hashedPassword)
 }
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"job_search_platform/pkg/apperr"
+)
+
+type legacyError struct {
+	kind apperr.Kind
+	code string
+}
+
+// legacyErrors gives every numeric code a kind and a stable string code
+var legacyErrors = map[int32]legacyError{
+	UNKNOWN_ERROR_CODE:                {apperr.KindInternal, "unknown_error"},
+	JWT_GENERATE_ERR_CODE:             {apperr.KindInternal, "jwt_generate_failed"},
+	GET_COOKIE_ERR_CODE:               {apperr.KindUnauthorized, "cookie_missing"},
+	TOKEN_VALIDATION_ERR_CODE:         {apperr.KindUnauthorized, "token_invalid"},
+	GENERATE_JWT_TOKEN_ERR_CODE:       {apperr.KindInternal, "token_generate_failed"},
+	MISSING_JWT_TOKEN_ERR_CODE:        {apperr.KindUnauthorized, "token_missing"},
+	AUTH_HEADER_NOT_PROVIDED_ERR_CODE: {apperr.KindUnauthorized, "auth_header_missing"},
+	AUTH_HEADER_FORMAT_ERR_CODE:       {apperr.KindUnauthorized, "auth_header_format"},
+	AUTH_HEADER_TYPE_ERR_CODE:         {apperr.KindUnauthorized, "auth_header_type"},
+	AUTO_LOGOUT_ERR_CODE:              {apperr.KindInternal, "auto_logout_failed"},
+	JWT_EXPIRES_ERR_CODE:              {apperr.KindUnauthorized, "token_expired"},
+	USER_EXISTS_ERR_CODE:              {apperr.KindConflict, "user_exists"},
+	INCORRECT_PASSWORD_ERR_CODE:       {apperr.KindUnauthorized, "incorrect_password"},
+	USER_NOT_EXISTS_ERR_CODE:          {apperr.KindNotFound, "user_not_found"},
+	EMPTY_FIELD_ERR_CODE:              {apperr.KindValidation, "empty_field"},
+	INVALID_URL_PARAM_ERR_CODE:        {apperr.KindInvalid, "invalid_url_param"},
+	USER_INFO_NOT_FOUND_CODE:          {apperr.KindNotFound, "user_info_not_found"},
+	INVITE_CODE_USED_ERR_CODE:         {apperr.KindConflict, "invite_code_used"},
+	SESSION_PARSING_ERR_CODE:          {apperr.KindUnauthorized, "session_invalid"},
+	TOKEN_REFRESH_ERR_CODE:            {apperr.KindUnauthorized, "token_refresh_failed"},
+	AUTH_HEADER_ERR_CODE:              {apperr.KindUnauthorized, "auth_header_invalid"},
+	SESSION_BLOCKED_ERR_CODE:          {apperr.KindUnauthorized, "session_blocked"},
+	CREATING_REQUEST_ERR_CODE:         {apperr.KindInternal, "request_build_failed"},
+	PARSING_RESPONSE_ERR_CODE:         {apperr.KindInternal, "response_parse_failed"},
+	SENDING_TOKEN_REFRESH_ERR_CODE:    {apperr.KindBadGateway, "token_refresh_unavailable"},
+	SESSION_NOT_FOUND_ERR_CODE:        {apperr.KindUnauthorized, "session_not_found"},
+	INVALID_DATA_ERR_CODE:             {apperr.KindInvalid, "invalid_data"},
+	PASSWORD_POLICY_ERR_CODE:          {apperr.KindValidation, "password_policy"},
+	PASSWORD_BREACHED_ERR_CODE:        {apperr.KindValidation, "password_breached"},
+	PASSWORD_REUSED_ERR_CODE:          {apperr.KindValidation, "password_reused"},
+	PASSWORD_HASHING_ERR_CODE:         {apperr.KindInternal, "password_hashing_failed"},
+	LOGIN_TOKEN_INVALID_ERR_CODE:      {apperr.KindUnauthorized, "login_link_invalid"},
+	LOGIN_TOKEN_SESSION_ERR_CODE:      {apperr.KindForbidden, "login_link_session"},
+	NOTIFICATION_MANDATORY_ERR_CODE:   {apperr.KindValidation, "notification_mandatory"},
+	UNSUBSCRIBE_TOKEN_ERR_CODE:        {apperr.KindInvalid, "unsubscribe_token_invalid"},
+	PERMISSION_DENIED_ERR_CODE:        {apperr.KindForbidden, "permission_denied"},
+	REQUEST_TOO_LARGE_ERR_CODE:        {apperr.KindTooLarge, "request_too_large"},
+	ROUTE_NOT_FOUND_ERR_CODE:          {apperr.KindNotFound, "route_not_found"},
+	UPSTREAM_BAD_GATEWAY_ERR_CODE:     {apperr.KindBadGateway, "upstream_bad_gateway"},
+	UPSTREAM_UNAVAILABLE_ERR_CODE:     {apperr.KindUnavailable, "upstream_unavailable"},
+	UPSTREAM_TIMEOUT_ERR_CODE:         {apperr.KindTimeout, "upstream_timeout"},
+	RATE_LIMIT_EXCEEDED_ERR_CODE:      {apperr.KindTooManyRequests, "rate_limit_exceeded"},
+	SESSION_EXPIRED_ERR_CODE:          {apperr.KindUnauthorized, "session_expired"},
+	CSRF_TOKEN_ERR_CODE:               {apperr.KindForbidden, "csrf_token_invalid"},
+	TOKEN_REVOKED_ERR_CODE:            {apperr.KindUnauthorized, "token_revoked"},
+	IDENTITY_HEADERS_ERR_CODE:         {apperr.KindUnauthorized, "identity_headers_invalid"},
+	USER_BANNED_ERR_CODE:              {apperr.KindForbidden, "user_banned"},
+}
+
+// Error turns a numeric code and its cause into a domain error. Database errors, including the
+// SQLSTATE codes from database.ErrorCode, are mapped by apperr.FromDatabase.
+func Error(code int32, err error) *apperr.Error {
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		if appErr.Legacy == 0 {
+			appErr.Legacy = code
+		}
+		return appErr
+	}
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) {
+		return apperr.FromDatabase(err, code)
+	}
+	legacy, ok := legacyErrors[code]
+	if !ok {
+		legacy = legacyErrors[UNKNOWN_ERROR_CODE]
+	}
+	return apperr.New(legacy.kind, legacy.code, err).WithLegacy(code)
+}
+
+// AbortErr writes the problem response of the code and stops the handler chain
+func AbortErr(ctx *gin.Context, code int32, err error) {
+	apperr.Abort(ctx, Error(code, err))
+}
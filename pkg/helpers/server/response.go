@@ -2,6 +2,8 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
+	"job_search_platform/pkg/apperr"
+	"job_search_platform/pkg/i18n"
 	"net/http"
 )
 
@@ -34,28 +36,57 @@ var (
 	PARSING_RESPONSE_ERR_CODE         int32 = 24
 	SENDING_TOKEN_REFRESH_ERR_CODE    int32 = 25
 	SESSION_NOT_FOUND_ERR_CODE        int32 = 26
+	PASSWORD_POLICY_ERR_CODE          int32 = 28 // Пароль не соответствует политике
+	PASSWORD_BREACHED_ERR_CODE        int32 = 29 // Пароль найден в утечках
+	PASSWORD_REUSED_ERR_CODE          int32 = 30 // Пароль уже использовался ранее
+	PASSWORD_HASHING_ERR_CODE         int32 = 31 // Ошибка хеширования пароля
+	LOGIN_TOKEN_INVALID_ERR_CODE      int32 = 32 // Ссылка для входа недействительна или истекла
+	LOGIN_TOKEN_SESSION_ERR_CODE      int32 = 33 // Ссылка для входа открыта в другой сессии
+	NOTIFICATION_MANDATORY_ERR_CODE   int32 = 34 // Обязательные уведомления нельзя отключить
+	UNSUBSCRIBE_TOKEN_ERR_CODE        int32 = 35 // Неверная ссылка отписки
+	PERMISSION_DENIED_ERR_CODE        int32 = 36 // Недостаточно прав
+	REQUEST_TOO_LARGE_ERR_CODE        int32 = 37 // Слишком большое тело запроса
+	ROUTE_NOT_FOUND_ERR_CODE          int32 = 38 // Маршрут не найден
+	UPSTREAM_BAD_GATEWAY_ERR_CODE     int32 = 39 // Сервис вернул некорректный ответ (502)
+	UPSTREAM_UNAVAILABLE_ERR_CODE     int32 = 40 // Сервис недоступен (503)
+	UPSTREAM_TIMEOUT_ERR_CODE         int32 = 41 // Сервис не ответил вовремя (504)
+	RATE_LIMIT_EXCEEDED_ERR_CODE      int32 = 42 // Превышен лимит запросов
+	SESSION_EXPIRED_ERR_CODE          int32 = 43 // Сессия истекла
+	CSRF_TOKEN_ERR_CODE               int32 = 44 // Неверный CSRF-токен
+	TOKEN_REVOKED_ERR_CODE            int32 = 45 // Токен отозван
+	IDENTITY_HEADERS_ERR_CODE         int32 = 46 // Неверная подпись заголовков пользователя от gateway
+	USER_BANNED_ERR_CODE              int32 = 47 // Пользователь заблокирован
 	UNKNOWN_ERROR_CODE                int32 = 1
 )
 
-func Response(err error, code int32, body interface{}) gin.H {
+// Response builds the common response body, error_message is localized by the request language
+func Response(ctx *gin.Context, err error, code int32, body interface{}) gin.H {
+	var errorMessage interface{}
+	if code != SUCCESS_CODE {
+		errorMessage = i18n.ErrorMessage(i18n.Lang(ctx), code)
+	}
 	if err != nil {
-		return gin.H{"error": err.Error(), "code": code, "body": body}
+		return gin.H{"error": err.Error(), "error_message": errorMessage, "code": code, "body": body}
 	}
-	return gin.H{"error": nil, "code": code, "body": body}
+	return gin.H{"error": nil, "error_message": errorMessage, "code": code, "body": body}
 }
 
+// HandlerErr answers with the status of the error kind, see Error
 func HandlerErr(ctx *gin.Context, errCode int32, err error) {
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, Response(err, errCode, nil))
-	} else {
-		ctx.JSON(http.StatusBadRequest, Response(nil, errCode, nil))
-	}
+	AbortErr(ctx, errCode, err)
 }
 
+// AuthHandlerErr answers 401 to every failed sign-in, see AuthError
 func AuthHandlerErr(ctx *gin.Context, errCode int32, err error) {
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, Response(err, errCode, nil))
-	} else {
-		ctx.JSON(http.StatusBadRequest, Response(nil, errCode, nil))
+	apperr.Abort(ctx, AuthError(errCode, err))
+}
+
+// AuthError is 401 for every failed sign-in, so the response does not tell
+// a missing user from a wrong password. Server errors keep their status.
+func AuthError(errCode int32, err error) *apperr.Error {
+	appErr := Error(errCode, err)
+	if appErr.Kind.Status() < http.StatusInternalServerError {
+		appErr.Kind = apperr.KindUnauthorized
 	}
+	return appErr
 }
@@ -0,0 +1,88 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	LangRu = "ru"
+	LangEn = "en"
+	LangKk = "kk"
+
+	DefaultLang = LangRu
+
+	unknownErrorKey = "errors.1"
+)
+
+// Langs lists the supported languages in the order they are preferred on a tie
+var Langs = []string{LangRu, LangEn, LangKk}
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// catalog maps a language to its flat "key" -> "message" dictionary
+var catalog = mustLoadCatalog()
+
+func mustLoadCatalog() map[string]map[string]string {
+	result := make(map[string]map[string]string, len(Langs))
+	for _, lang := range Langs {
+		data, err := localesFS.ReadFile(path.Join("locales", lang+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: cannot read %s catalog: %v", lang, err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: cannot parse %s catalog: %v", lang, err))
+		}
+		result[lang] = messages
+	}
+	return result
+}
+
+// IsSupported reports whether lang has a message catalog
+func IsSupported(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// Normalize turns "en-US", "EN" or "kk_KZ" into a supported base language, "" when unsupported
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if idx := strings.IndexAny(lang, "-_"); idx >= 0 {
+		lang = lang[:idx]
+	}
+	if IsSupported(lang) {
+		return lang
+	}
+	return ""
+}
+
+// T returns the message for key in lang, falling back to the default language and then to the key itself.
+// args are applied with fmt.Sprintf.
+func T(lang string, key string, args ...interface{}) string {
+	message, ok := catalog[lang][key]
+	if !ok {
+		message, ok = catalog[DefaultLang][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// ErrorMessage returns the human readable text of a response status code,
+// codes without a translation get the "unknown error" text
+func ErrorMessage(lang string, code int32) string {
+	key := fmt.Sprintf("errors.%d", code)
+	if message := T(lang, key); message != key {
+		return message
+	}
+	return T(lang, unknownErrorKey)
+}
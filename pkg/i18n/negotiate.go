@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"github.com/gin-gonic/gin"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	AcceptLanguageHeader = "Accept-Language"
+
+	langContextKey = "lang"
+)
+
+// Negotiate picks the response language: a stored user preference wins,
+// then the best supported entry of the Accept-Language header, then DefaultLang.
+func Negotiate(preferred string, acceptLanguage string) string {
+	if lang := Normalize(preferred); lang != "" {
+		return lang
+	}
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if lang := Normalize(tag); lang != "" {
+			return lang
+		}
+	}
+	return DefaultLang
+}
+
+// SetLang stores the stored user preference in the request context, e.g. from the JWT payload
+func SetLang(ctx *gin.Context, lang string) {
+	if lang = Normalize(lang); lang != "" {
+		ctx.Set(langContextKey, lang)
+	}
+}
+
+// Lang returns the language of the current request
+func Lang(ctx *gin.Context) string {
+	if ctx == nil {
+		return DefaultLang
+	}
+	return Negotiate(ctx.GetString(langContextKey), ctx.GetHeader(AcceptLanguageHeader))
+}
+
+type weightedTag struct {
+	tag    string
+	weight float64
+}
+
+// parseAcceptLanguage returns the language tags of the header sorted by their q-value, "*" and q=0 are skipped
+func parseAcceptLanguage(header string) []string {
+	var tags []weightedTag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				weight = q
+			}
+		}
+		if weight <= 0 {
+			continue
+		}
+		tags = append(tags, weightedTag{tag: tag, weight: weight})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].weight > tags[j].weight
+	})
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, tag.tag)
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"path"
+)
+
+const (
+	EmailVerifyEmail = "verify_email"
+	EmailLoginLink   = "login_link"
+)
+
+//go:embed templates/*/*.html
+var templatesFS embed.FS
+
+var emailTemplates = mustLoadTemplates()
+
+func mustLoadTemplates() map[string]*template.Template {
+	result := make(map[string]*template.Template, len(Langs))
+	for _, lang := range Langs {
+		tmpl, err := template.ParseFS(templatesFS, path.Join("templates", lang, "*.html"))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: cannot parse %s email templates: %v", lang, err))
+		}
+		result[lang] = tmpl
+	}
+	return result
+}
+
+// RenderEmail renders the email template name for lang and returns its subject and html body.
+// Missing translations fall back to DefaultLang.
+func RenderEmail(lang string, name string, data interface{}) (subject string, content string, err error) {
+	if lang = Normalize(lang); lang == "" {
+		lang = DefaultLang
+	}
+	tmpl := emailTemplates[lang].Lookup(name + ".html")
+	if tmpl == nil {
+		lang = DefaultLang
+		tmpl = emailTemplates[lang].Lookup(name + ".html")
+	}
+	if tmpl == nil {
+		return "", "", fmt.Errorf("email template %q not found", name)
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("cannot render email template %q: %w", name, err)
+	}
+	return T(lang, "email."+name+".subject"), buf.String(), nil
+}
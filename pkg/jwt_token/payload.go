@@ -23,6 +23,8 @@ type Payload struct {
 	//IsSuperuser bool      `json:"is_superuser"`
 	//IsStaff     bool      `json:"is_staff"`
 	Groups    []string  `json:"roles"`
+	UserType  string    `json:"user_type"`
+	LangCode  string    `json:"lang_code"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
@@ -43,6 +45,8 @@ func NewPayload(user common.UserResponse, tokenType string, duration time.Durati
 		//IsSuperuser: user.IsSuperuser,
 		//IsStaff:     user.IsStaff,
 		Groups:    user.Groups,
+		UserType:  user.UserType,
+		LangCode:  user.LangCode,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
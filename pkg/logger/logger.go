@@ -1,33 +1,86 @@
 package logger
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/rs/zerolog"
-	"log"
-
+	"github.com/rs/zerolog/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
+	"job_search_platform/pkg/config"
+	"job_search_platform/pkg/tracing"
 	"os"
+	"time"
 )
 
-func ConfigureLogger(environment string) (zerolog.Logger, *os.File) {
-	var logger zerolog.Logger
-	var file *os.File
-	var err error
-	const filename = "./logs/app.log"
-
-	// Create logger based on environment
-	if environment == "DEV" {
-		logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
-	} else {
-		// Ensure the logs directory exists
-		if err = os.MkdirAll("./logs", os.ModePerm); err != nil {
-			log.Fatalf("error creating logs directory: %v", err)
-		}
+const (
+	FormatJson    = "json"
+	FormatConsole = "console"
+
+	// defaultFile is used outside of DEV when LOG_FILE is empty
+	defaultFile = "./logs/app.log"
+)
 
-		// Open or create the log file
-		if file, err = os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
-			log.Fatalf("error opening/creating log file: %v", err)
+// ConfigureLogger builds the service logger from the LOG_* settings and makes it the global
+// and the default context logger. The returned closer flushes the log file.
+func ConfigureLogger(config config.Common) (zerolog.Logger, io.Closer) {
+	level, err := zerolog.ParseLevel(config.LogLevel)
+	if err != nil || level == zerolog.NoLevel {
+		level = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(level)
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+
+	filename := config.LogFile
+	if filename == "" && config.Environment != "DEV" {
+		filename = defaultFile
+	}
+	var out io.Writer = os.Stderr
+	var closer io.Closer = io.NopCloser(nil)
+	switch filename {
+	case "", "stderr":
+	case "stdout":
+		out = os.Stdout
+	default:
+		// файл ротируется по размеру, старые части сжимаются
+		file := &lumberjack.Logger{
+			Filename:   filename,
+			MaxSize:    config.LogMaxSizeMb,
+			MaxBackups: config.LogMaxBackups,
+			MaxAge:     config.LogMaxAgeDays,
+			Compress:   config.LogCompress,
 		}
-		logger = zerolog.New(file).With().Timestamp().Logger()
+		out, closer = file, file
 	}
+	format := config.LogFormat
+	if format == "" && config.Environment == "DEV" {
+		format = FormatConsole
+	}
+	if format == FormatConsole {
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.DateTime, NoColor: out != os.Stderr && out != os.Stdout}
+	}
+
+	logger := zerolog.New(&redactWriter{next: out}).With().Timestamp().Logger().Hook(tracing.LogHook{})
+	log.Logger = logger
+	zerolog.DefaultContextLogger = &logger
+	return logger, closer
+}
+
+// Annotate adds a field to the request logger stored in ctx, it does nothing outside of a request
+// so that the default logger is never changed
+func Annotate(ctx context.Context, key, value string) {
+	logger := zerolog.Ctx(ctx)
+	if logger == zerolog.DefaultContextLogger || logger.GetLevel() == zerolog.Disabled {
+		return
+	}
+	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str(key, value)
+	})
+}
 
-	return logger, file
+// Fingerprint identifies a secret, such as a session id, in the logs without revealing it
+func Fingerprint(value string) string {
+	digest := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(digest[:8])
 }
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched as parts of the lower-case field name
+var sensitiveKeys = []string{"password", "token", "secret", "cookie", "authorization", "api_key", "apikey"}
+
+var (
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]+`)
+)
+
+// redactWriter masks the values of sensitive fields and the tokens inside any string
+// before the line reaches the output. Lines without anything to mask are written as is.
+type redactWriter struct {
+	next io.Writer
+}
+
+func (w *redactWriter) Write(p []byte) (int, error) {
+	if !mayContainSecret(p) {
+		return w.next.Write(p)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(p, &entry); err != nil {
+		return w.next.Write(jwtPattern.ReplaceAll(p, []byte(redacted)))
+	}
+	line, err := json.Marshal(redactValue("", entry))
+	if err != nil {
+		return 0, err
+	}
+	if _, err = w.next.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func mayContainSecret(p []byte) bool {
+	lower := bytes.ToLower(p)
+	if bytes.Contains(lower, []byte("eyj")) || bytes.Contains(lower, []byte("bearer")) {
+		return true
+	}
+	for _, key := range sensitiveKeys {
+		if bytes.Contains(lower, []byte(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(key string, value interface{}) interface{} {
+	if isSensitive(key) {
+		return redacted
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = redactValue(k, item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue("", item)
+		}
+		return v
+	case string:
+		v = jwtPattern.ReplaceAllString(v, redacted)
+		return bearerPattern.ReplaceAllString(v, "Bearer "+redacted)
+	}
+	return value
+}
+
+func isSensitive(key string) bool {
+	if key == "" {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -14,14 +14,38 @@ type EmailSender interface {
 		cc []string,
 		bcc []string,
 		attachFiles []string,
+		opts ...EmailOption,
 	) error
 }
 
+// EmailOption adjusts the message before it is sent, e.g. adds headers
+type EmailOption func(e *email.Email)
+
+func WithHeader(key, value string) EmailOption {
+	return func(e *email.Email) {
+		e.Headers.Set(key, value)
+	}
+}
+
+// WithListUnsubscribe adds the RFC 2369 List-Unsubscribe header and the RFC 8058 one-click marker,
+// mail clients then show an "unsubscribe" button that POSTs to unsubscribeUrl.
+func WithListUnsubscribe(unsubscribeUrl string) EmailOption {
+	return func(e *email.Email) {
+		e.Headers.Set("List-Unsubscribe", fmt.Sprintf("<%s>", unsubscribeUrl))
+		e.Headers.Set("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+}
+
 const (
 	smtpAuthAddress   = "smtp.gmail.com"
 	smtpServerAddress = "smtp.gmail.com:587"
 )
 
+// ServerAddress is the SMTP server the gmail sender connects to
+func ServerAddress() string {
+	return smtpServerAddress
+}
+
 type GmailSender struct {
 	name              string
 	fromEmailAddress  string
@@ -39,6 +63,7 @@ func (sender *GmailSender) SendEmail(
 	cc []string,
 	bcc []string,
 	attachFiles []string,
+	opts ...EmailOption,
 ) error {
 	e := email.NewEmail()
 	e.From = fmt.Sprintf("%s <%s>", sender.name, sender.fromEmailAddress)
@@ -47,6 +72,9 @@ func (sender *GmailSender) SendEmail(
 	e.To = to                //  Список получателей
 	e.Cc = cc                // Список получателей копий.
 	e.Bcc = bcc              // Список получателей BCC
+	for _, opt := range opts {
+		opt(e)
+	}
 
 	// Список путей к файлам, которые необходимо прикрепить к электронному письму.
 	for _, f := range attachFiles {
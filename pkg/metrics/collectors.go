@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// poolCollector reads the pgxpool statistics on every scrape
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+// RegisterPgxPool exports the connection pool statistics
+func RegisterPgxPool(pool *pgxpool.Pool) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+	}
+	prometheus.MustRegister(&poolCollector{
+		pool:            pool,
+		acquiredConns:   desc("acquired_conns", "Connections currently acquired from the pool."),
+		idleConns:       desc("idle_conns", "Idle connections in the pool."),
+		totalConns:      desc("total_conns", "Total connections in the pool."),
+		maxConns:        desc("max_conns", "Maximum size of the pool."),
+		acquireCount:    desc("acquire_total", "Successful acquires from the pool."),
+		acquireDuration: desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyAcquire:    desc("empty_acquire_total", "Acquires that waited for a connection because the pool was empty."),
+		canceledAcquire: desc("canceled_acquire_total", "Acquires canceled by the context."),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
+
+// queueCollector reads the asynq queue sizes from redis on every scrape
+type queueCollector struct {
+	inspector *asynq.Inspector
+	queues    []string
+
+	tasks   *prometheus.Desc
+	latency *prometheus.Desc
+}
+
+// RegisterQueues exports the depth of the asynq queues by task state
+func RegisterQueues(redisOpt asynq.RedisClientOpt, queues ...string) {
+	prometheus.MustRegister(&queueCollector{
+		inspector: asynq.NewInspector(redisOpt),
+		queues:    queues,
+		tasks: prometheus.NewDesc(prometheus.BuildFQName(namespace, "queue", "tasks"),
+			"Tasks in the asynq queue by state.", []string{"queue", "state"}, nil),
+		latency: prometheus.NewDesc(prometheus.BuildFQName(namespace, "queue", "latency_seconds"),
+			"Age of the oldest pending task in the asynq queue.", []string{"queue"}, nil),
+	})
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tasks
+	ch <- c.latency
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, queue := range c.queues {
+		info, err := c.inspector.GetQueueInfo(queue)
+		if err != nil {
+			// очередь появляется в redis только после первой задачи
+			log.Debug().Err(err).Str("queue", queue).Msg("cannot get queue info")
+			continue
+		}
+		states := map[string]int{
+			"pending":   info.Pending,
+			"active":    info.Active,
+			"scheduled": info.Scheduled,
+			"retry":     info.Retry,
+			"archived":  info.Archived,
+		}
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.GaugeValue, float64(count), queue, state)
+		}
+		ch <- prometheus.MustNewConstMetric(c.latency, prometheus.GaugeValue, info.Latency.Seconds(), queue)
+	}
+}
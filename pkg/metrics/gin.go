@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	routeContextKey = "metricsRoute"
+	// unmatchedRoute keeps requests to unknown paths in a single series
+	unmatchedRoute = "unmatched"
+	// otherMethod keeps the non-standard methods of the clients in a single series
+	otherMethod = "OTHER"
+)
+
+// SetRoute overrides the route label of the request, the gateway serves every route through
+// one /*path handler and labels the requests by the matched route table prefix
+func SetRoute(ctx *gin.Context, route string) {
+	ctx.Set(routeContextKey, route)
+}
+
+// Route returns the route label of the served request
+func Route(ctx *gin.Context) string {
+	route := ctx.GetString(routeContextKey)
+	if route == "" {
+		route = ctx.FullPath()
+	}
+	if route == "" {
+		route = unmatchedRoute
+	}
+	return route
+}
+
+// Middleware records the RED metrics of the requests served by service
+func Middleware(service string) gin.HandlerFunc {
+	inFlight := HttpRequestsInFlight.WithLabelValues(service)
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+		ctx.Next()
+
+		route := Route(ctx)
+		method := Method(ctx.Request.Method)
+		HttpRequests.WithLabelValues(service, method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+		HttpRequestDuration.WithLabelValues(service, method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Method returns the method label, the client may send any method
+func Method(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return otherMethod
+}
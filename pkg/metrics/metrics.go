@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "jsp"
+
+// RED metrics of the HTTP servers, route is the gin route template or the gateway route prefix
+var (
+	HttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route and status code.",
+	}, []string{"service", "method", "route", "status"})
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method", "route"})
+	HttpRequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being served.",
+	}, []string{"service"})
+)
+
+// Gateway upstream calls, status is "error" when no response was received
+var (
+	UpstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests forwarded by the gateway by upstream service and status code.",
+	}, []string{"upstream", "status"})
+	UpstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of a single attempt to an upstream service.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"upstream"})
+)
+
+// Background tasks, status is success or failure
+var (
+	TasksProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_processed_total",
+		Help:      "Processed asynq tasks by type and status.",
+	}, []string{"task_type", "status"})
+	TaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Asynq task processing latency by type.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"task_type"})
+)
+
+// Gateway sessions and access token refresh
+var (
+	SessionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sessions_active",
+		Help:      "Active gateway sessions as of the last cleanup job.",
+	})
+	SessionsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_created_total",
+		Help:      "Gateway sessions created.",
+	})
+	SessionsEnded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_ended_total",
+		Help:      "Gateway sessions ended by reason: logout, rotated or expired.",
+	}, []string{"reason"})
+	TokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_refreshes_total",
+		Help:      "Access token refreshes by outcome: refreshed, reused or failed.",
+	}, []string{"outcome"})
+)
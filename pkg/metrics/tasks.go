@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"context"
+	"github.com/hibiken/asynq"
+	"time"
+)
+
+// TaskMiddleware records the outcome and latency of every task handled by the mux
+func TaskMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		start := time.Now()
+		err := next.ProcessTask(ctx, task)
+		status := "success"
+		if err != nil {
+			status = "failure"
+		}
+		TasksProcessed.WithLabelValues(task.Type(), status).Inc()
+		TaskDuration.WithLabelValues(task.Type()).Observe(time.Since(start).Seconds())
+		return err
+	})
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// DeadlineHeader carries the absolute deadline of the request set by the gateway route timeout
+const DeadlineHeader = "X-Request-Deadline"
+
+// Deadline bounds the request context by the deadline the gateway propagated,
+// so the service stops working on a request nobody waits for anymore
+func Deadline() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		deadline, err := time.Parse(time.RFC3339Nano, ctx.GetHeader(DeadlineHeader))
+		if err != nil {
+			ctx.Next()
+			return
+		}
+		reqCtx, cancel := context.WithDeadline(ctx.Request.Context(), deadline)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
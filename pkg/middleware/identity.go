@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/i18n"
+	"job_search_platform/pkg/jwt_token"
+	"job_search_platform/pkg/logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identity headers are set by the gateway from the verified access token
+const (
+	UserIdHeader    = "X-User-ID"
+	UserEmailHeader = "X-User-Email"
+	UserRolesHeader = "X-User-Roles"
+	UserTypeHeader  = "X-User-Type"
+	UserLangHeader  = "X-User-Lang"
+
+	IdentityTimestampHeader = "X-Identity-Timestamp"
+	IdentitySignatureHeader = "X-Identity-Signature"
+
+	minIdentityKeySize = 32
+)
+
+var ErrInvalidIdentity = errors.New("identity headers are invalid")
+
+// identityHeaders are signed in this order, a client can not set any of them through the gateway
+var identityHeaders = []string{
+	UserIdHeader, UserEmailHeader, UserRolesHeader, UserTypeHeader, UserLangHeader,
+	server.SessionIdHeader, RequestIdHeader, IdentityTimestampHeader,
+}
+
+// IdentitySigner signs the identity headers of a request forwarded by the gateway with HMAC,
+// the signature covers the method and the path and is accepted for maxAge.
+type IdentitySigner struct {
+	secretKey []byte
+	maxAge    time.Duration
+}
+
+func NewIdentitySigner(secretKey string, maxAge time.Duration) (*IdentitySigner, error) {
+	if len(secretKey) < minIdentityKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minIdentityKeySize)
+	}
+	return &IdentitySigner{secretKey: []byte(secretKey), maxAge: maxAge}, nil
+}
+
+// StripIdentityHeaders removes the identity headers sent by the client
+func StripIdentityHeaders(header http.Header) {
+	for _, key := range identityHeaders {
+		header.Del(key)
+	}
+	header.Del(IdentitySignatureHeader)
+}
+
+// Sign sets the identity headers of the payload, nil for an anonymous session, and signs them together
+// with the session and request id already set on the request
+func (signer *IdentitySigner) Sign(req *http.Request, payload *jwt_token.Payload) {
+	if payload != nil {
+		req.Header.Set(UserIdHeader, payload.UserId.String())
+		req.Header.Set(UserEmailHeader, payload.Email)
+		req.Header.Set(UserRolesHeader, strings.Join(payload.Groups, ","))
+		req.Header.Set(UserTypeHeader, payload.UserType)
+		req.Header.Set(UserLangHeader, payload.LangCode)
+	}
+	req.Header.Set(IdentityTimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(IdentitySignatureHeader, base64.RawURLEncoding.EncodeToString(signer.sign(req)))
+}
+
+// Verify checks the signature and returns the payload, nil when the gateway forwarded an anonymous request
+func (signer *IdentitySigner) Verify(req *http.Request) (*jwt_token.Payload, error) {
+	signature, err := base64.RawURLEncoding.DecodeString(req.Header.Get(IdentitySignatureHeader))
+	if err != nil || !hmac.Equal(signature, signer.sign(req)) {
+		return nil, ErrInvalidIdentity
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(IdentityTimestampHeader), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidIdentity
+	}
+	issuedAt := time.Unix(timestamp, 0)
+	if age := time.Since(issuedAt); age > signer.maxAge || age < -signer.maxAge {
+		return nil, ErrInvalidIdentity
+	}
+	if req.Header.Get(UserIdHeader) == "" {
+		return nil, nil
+	}
+	userId, err := uuid.Parse(req.Header.Get(UserIdHeader))
+	if err != nil {
+		return nil, ErrInvalidIdentity
+	}
+	var groups []string
+	if roles := req.Header.Get(UserRolesHeader); roles != "" {
+		groups = strings.Split(roles, ",")
+	}
+	return &jwt_token.Payload{
+		TokenType: "access",
+		UserId:    userId,
+		Email:     req.Header.Get(UserEmailHeader),
+		Groups:    groups,
+		UserType:  req.Header.Get(UserTypeHeader),
+		LangCode:  req.Header.Get(UserLangHeader),
+		IssuedAt:  issuedAt,
+		ExpiredAt: issuedAt.Add(signer.maxAge),
+	}, nil
+}
+
+func (signer *IdentitySigner) sign(req *http.Request) []byte {
+	mac := hmac.New(sha256.New, signer.secretKey)
+	mac.Write([]byte(req.Method + "\n" + req.URL.Path))
+	for _, key := range identityHeaders {
+		mac.Write([]byte("\n" + req.Header.Get(key)))
+	}
+	return mac.Sum(nil)
+}
+
+// Identity trusts the identity headers signed by the gateway and falls back to the bearer token
+// for requests without them. A nil signer always parses the bearer token.
+func Identity(signer *IdentitySigner, tokenMaker jwt_token.Maker) gin.HandlerFunc {
+	jwtDeserializer := JWTDeserializer(tokenMaker)
+	return func(ctx *gin.Context) {
+		if signer == nil || ctx.GetHeader(IdentitySignatureHeader) == "" {
+			jwtDeserializer(ctx)
+			return
+		}
+		jwtPayload, err := signer.Verify(ctx.Request)
+		if err != nil {
+			server.AuthHandlerErr(ctx, server.IDENTITY_HEADERS_ERR_CODE, err)
+			return
+		}
+		if jwtPayload == nil {
+			// подпись есть, но gateway не аутентифицировал запрос
+			jwtDeserializer(ctx)
+			return
+		}
+		ctx.Set("jwtTokenPayload", jwtPayload)
+		logger.Annotate(ctx, "user_id", jwtPayload.UserId.String())
+		i18n.SetLang(ctx, jwtPayload.LangCode)
+		ctx.Next()
+	}
+}
@@ -3,8 +3,9 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/i18n"
 	"job_search_platform/pkg/jwt_token"
-	"net/http"
+	"job_search_platform/pkg/logger"
 	"strings"
 )
 
@@ -15,18 +16,21 @@ func JWTDeserializer(tokenMaker jwt_token.Maker) gin.HandlerFunc {
 		fields := strings.Fields(authorizationHeader)
 
 		if len(fields) != 2 || fields[0] != "Bearer" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, server.Response(nil, server.AUTH_HEADER_ERR_CODE, nil))
+			server.AuthHandlerErr(ctx, server.AUTH_HEADER_ERR_CODE, nil)
 			return
 		}
 		accessToken = fields[1]
 		jwtPayload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
 			statusCode := tokenMaker.GetErrorCode(err)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, server.Response(err, statusCode, nil))
+			server.AuthHandlerErr(ctx, statusCode, err)
 			return
 		}
 
 		ctx.Set("jwtTokenPayload", jwtPayload)
+		logger.Annotate(ctx, "user_id", jwtPayload.UserId.String())
+		// язык из профиля пользователя важнее Accept-Language
+		i18n.SetLang(ctx, jwtPayload.LangCode)
 		ctx.Next()
 	}
 }
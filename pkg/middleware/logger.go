@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"job_search_platform/pkg/helpers/server"
+	"job_search_platform/pkg/logger"
+	"net/http"
+	"time"
+)
+
+// RequestLogger stores a child of base with the request id, trace id and session fingerprint in the request context
+// and writes an access log line when the request is served. The handlers log with log.Ctx(ctx),
+// the user id and the route are added by logger.Annotate once they are known.
+func RequestLogger(base zerolog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		loggerCtx := base.With().Str("request_id", GetRequestId(ctx))
+		if spanContext := trace.SpanContextFromContext(ctx.Request.Context()); spanContext.IsValid() {
+			loggerCtx = loggerCtx.Str("trace_id", spanContext.TraceID().String())
+		}
+		if sessionId := ctx.GetHeader(server.SessionIdHeader); sessionId != "" {
+			loggerCtx = loggerCtx.Str("session", logger.Fingerprint(sessionId))
+		}
+		if route := ctx.FullPath(); route != "" {
+			loggerCtx = loggerCtx.Str("route", route)
+		}
+		reqCtx := loggerCtx.Logger().WithContext(ctx.Request.Context())
+		// Annotate меняет именно этот экземпляр, строка доступа пишется им же
+		logger := zerolog.Ctx(reqCtx)
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		var event *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = logger.Error()
+		case status >= http.StatusBadRequest:
+			event = logger.Warn()
+		default:
+			event = logger.Info()
+		}
+		if len(ctx.Errors) > 0 {
+			event = event.Err(ctx.Errors.Last())
+		}
+		event.Str("method", ctx.Request.Method).
+			Str("path", ctx.Request.URL.Path).
+			Int("status", status).
+			Int("size", max(ctx.Writer.Size(), 0)).
+			Str("client_ip", ctx.ClientIP()).
+			Dur("latency", time.Since(start)).
+			Msg("request")
+	}
+}
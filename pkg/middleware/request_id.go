@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIdHeader = "X-Request-ID"
+
+	requestIdContextKey = "requestId"
+	maxRequestIdLength  = 128
+)
+
+// RequestId keeps the X-Request-ID of the incoming request or generates a new one
+// and echoes it in the response, so a request can be followed through every service
+func RequestId() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader(RequestIdHeader)
+		if requestId == "" || len(requestId) > maxRequestIdLength {
+			requestId = uuid.NewString()
+		}
+		ctx.Set(requestIdContextKey, requestId)
+		ctx.Header(RequestIdHeader, requestId)
+		ctx.Next()
+	}
+}
+
+func GetRequestId(ctx *gin.Context) string {
+	return ctx.GetString(requestIdContextKey)
+}
@@ -0,0 +1,36 @@
+package notifications
+
+import "context"
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelInApp = "in_app"
+)
+
+const (
+	CategorySecurity  = "security"
+	CategoryJobAlerts = "job_alerts"
+	CategoryMarketing = "marketing"
+	CategoryMessages  = "messages"
+)
+
+var (
+	Channels   = []string{ChannelEmail, ChannelSMS, ChannelInApp}
+	Categories = []string{CategorySecurity, CategoryJobAlerts, CategoryMarketing, CategoryMessages}
+)
+
+// PreferencesChecker is used by the task processor before sending a message
+type PreferencesChecker interface {
+	IsEnabled(ctx context.Context, email string, channel string, category string) (bool, error)
+}
+
+// IsMandatory reports whether the category cannot be switched off (password resets, login links and so on)
+func IsMandatory(category string) bool {
+	return category == CategorySecurity
+}
+
+// DefaultEnabled is used when the user has not saved a preference yet, marketing is opt-in
+func DefaultEnabled(channel string, category string) bool {
+	return category != CategoryMarketing
+}
@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const minSecretKeySize = 32
+
+var ErrInvalidUnsubscribeToken = errors.New("unsubscribe token is invalid")
+
+type UnsubscribeClaims struct {
+	Email    string `json:"email"`
+	Channel  string `json:"channel"`
+	Category string `json:"category"`
+}
+
+// UnsubscribeSigner creates HMAC-signed tokens for one-click unsubscribe links,
+// the link does not expire so that it keeps working in old emails.
+type UnsubscribeSigner struct {
+	secretKey []byte
+}
+
+func NewUnsubscribeSigner(secretKey string) (*UnsubscribeSigner, error) {
+	if len(secretKey) < minSecretKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+	}
+	return &UnsubscribeSigner{secretKey: []byte(secretKey)}, nil
+}
+
+func (signer *UnsubscribeSigner) CreateToken(claims UnsubscribeClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	return encodedPayload + "." + base64.RawURLEncoding.EncodeToString(signer.sign(encodedPayload)), nil
+}
+
+func (signer *UnsubscribeSigner) VerifyToken(token string) (*UnsubscribeClaims, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signer.sign(encodedPayload)) {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	var claims UnsubscribeClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	if IsMandatory(claims.Category) {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+	return &claims, nil
+}
+
+func (signer *UnsubscribeSigner) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, signer.secretKey)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,89 @@
+package openapi
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Write writes the indented document, the committed file is written by `<binary> openapi`
+func Write(w io.Writer, doc *Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// Check compares the generated document with the committed one
+func Check(path string, generated *Document) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read the specification: %w", err)
+	}
+	var committed Document
+	if err = json.Unmarshal(data, &committed); err != nil {
+		return fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	// сравнение через JSON, чтобы пустые и отсутствующие поля не отличались
+	encoded, err := json.Marshal(generated)
+	if err != nil {
+		return err
+	}
+	var actual Document
+	if err = json.Unmarshal(encoded, &actual); err != nil {
+		return err
+	}
+	problems := Diff(&committed, &actual)
+	if len(problems) == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("%s does not match the handlers, regenerate it with `openapi -o %s`:", path, path)
+	for _, problem := range problems {
+		message += "\n  " + problem
+	}
+	return fmt.Errorf("%s", message)
+}
+
+// RunCommand handles `<binary> openapi [-o file] [--check file]`. It returns false for other arguments,
+// otherwise the exit code. The check fails when the request or response types of a handler,
+// or the set of routes, drift from the committed specification. Run it next to go vet.
+func RunCommand(args []string, generate func() (*Document, error)) (int, bool) {
+	if len(args) < 1 || args[0] != "openapi" {
+		return 0, false
+	}
+	flags := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	output := flags.String("o", "", "write the specification to the file instead of stdout")
+	check := flags.String("check", "", "compare the specification with the committed file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2, true
+	}
+
+	doc, err := generate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1, true
+	}
+	if *check != "" {
+		if err := Check(*check, doc); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1, true
+		}
+		return 0, true
+	}
+	w := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1, true
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := Write(w, doc); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,51 @@
+package openapi
+
+import (
+	"github.com/gin-gonic/gin"
+	"job_search_platform/pkg/helpers/server"
+	"net/http"
+	"reflect"
+)
+
+// NoBody is the request type of a handler without a JSON body and the response type of a null body
+type NoBody struct{}
+
+var noBodyType = reflect.TypeOf(NoBody{})
+
+// Handler is a handler with the types of its JSON bodies. The request is bound and validated before the call,
+// the response is the body of server.Response. A returned error is written as a problem, see server.Error.
+type Handler[Req, Resp any] func(ctx *gin.Context, request *Req) (*Resp, error)
+
+// Endpoint is a handler registered through the Group, the spec takes the body types from it
+type Endpoint struct {
+	handler  gin.HandlerFunc
+	name     string
+	request  reflect.Type
+	response reflect.Type
+}
+
+// Typed wraps the handler, so the documented types are the ones it binds and returns
+func Typed[Req, Resp any](handler Handler[Req, Resp]) Endpoint {
+	endpoint := Endpoint{
+		name:     handlerName(handler),
+		request:  reflect.TypeOf((*Req)(nil)).Elem(),
+		response: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+	bind := endpoint.request != noBodyType
+	endpoint.handler = func(ctx *gin.Context) {
+		var request Req
+		if bind {
+			if err := ctx.ShouldBindJSON(&request); err != nil {
+				server.AbortErr(ctx, server.INVALID_DATA_ERR_CODE, err)
+				return
+			}
+		}
+		response, err := handler(ctx, &request)
+		if err != nil {
+			server.AbortErr(ctx, server.UNKNOWN_ERROR_CODE, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, server.Response(ctx, nil, server.SUCCESS_CODE, response))
+	}
+	return endpoint
+}
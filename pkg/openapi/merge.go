@@ -0,0 +1,105 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// New returns an empty document, the gateway fills it with Merge
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: version},
+		Paths:      make(map[string]PathItem),
+		Components: Components{Schemas: make(map[string]*Schema), SecuritySchemes: make(map[string]*SecurityScheme)},
+	}
+}
+
+// Merge adds the operations of source accepted by keep, keep may change the operation.
+// The schemas are renamed to <namespace>.<name>, so the services do not clash. The security
+// schemes are not merged: the operations are served by the aggregator, keep sets their security.
+func (doc *Document) Merge(source *Document, namespace string, keep func(path, method string, operation *Operation) bool) {
+	rename := func(schema *Schema) {
+		if strings.HasPrefix(schema.Ref, schemasPrefix) {
+			schema.Ref = schemasPrefix + namespace + "." + strings.TrimPrefix(schema.Ref, schemasPrefix)
+		}
+	}
+	for path, item := range source.Paths {
+		for method, operation := range item {
+			operation.Security = nil
+			if !keep(path, method, operation) {
+				continue
+			}
+			for _, schema := range operation.schemas() {
+				schema.walk(rename)
+			}
+			if _, ok := doc.Paths[path]; !ok {
+				doc.Paths[path] = make(PathItem)
+			}
+			doc.Paths[path][method] = operation
+		}
+	}
+	for name, schema := range source.Components.Schemas {
+		schema.walk(rename)
+		doc.Components.Schemas[namespace+"."+name] = schema
+	}
+}
+
+// Diff lists the operations and schemas that differ, an empty result means the documents match
+func Diff(expected, actual *Document) []string {
+	var problems []string
+	for _, key := range unionKeys(operations(expected), operations(actual)) {
+		if problem := compare("operation", key, operations(expected)[key], operations(actual)[key]); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	for _, name := range unionKeys(expected.Components.Schemas, actual.Components.Schemas) {
+		if problem := compare("schema", name, expected.Components.Schemas[name], actual.Components.Schemas[name]); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+func operations(doc *Document) map[string]*Operation {
+	result := make(map[string]*Operation)
+	for path, item := range doc.Paths {
+		for method, operation := range item {
+			result[strings.ToUpper(method)+" "+path] = operation
+		}
+	}
+	return result
+}
+
+func compare[T any](kind, key string, expected, actual *T) string {
+	switch {
+	case expected == nil:
+		return fmt.Sprintf("%s %s is not in the expected document", kind, key)
+	case actual == nil:
+		return fmt.Sprintf("%s %s is missing", kind, key)
+	}
+	expectedJson, _ := json.Marshal(expected)
+	actualJson, _ := json.Marshal(actual)
+	if string(expectedJson) != string(actualJson) {
+		return fmt.Sprintf("%s %s changed:\n    expected %s\n    actual   %s", kind, key, expectedJson, actualJson)
+	}
+	return ""
+}
+
+func unionKeys[T any](first, second map[string]T) []string {
+	keys := make(map[string]bool, len(first)+len(second))
+	for key := range first {
+		keys[key] = true
+	}
+	for key := range second {
+		keys[key] = true
+	}
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
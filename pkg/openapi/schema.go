@@ -0,0 +1,178 @@
+package openapi
+
+import (
+	"github.com/google/uuid"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// rulePatterns describe the custom rules of pkg/validation
+var rulePatterns = map[string]*Schema{
+	"phone":        {Pattern: `^\+[1-9][0-9]{6,14}$`, Description: "E.164 phone number"},
+	"country_code": {Pattern: `^[A-Z]{2}$`, Description: "ISO 3166-1 alpha-2 country code"},
+	"password":     {Description: "must satisfy the password policy"},
+}
+
+// schemaOf returns the schema of the type, structs are added to the components and referenced
+func (spec *Spec) schemaOf(valueType reflect.Type) *Schema {
+	switch valueType {
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch valueType.Kind() {
+	case reflect.Ptr:
+		schema := spec.schemaOf(valueType.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: spec.schemaOf(valueType.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: spec.schemaOf(valueType.Elem())}
+	case reflect.Struct:
+		return SchemaRef(spec.component(valueType))
+	}
+	// interface{}: любое значение
+	return &Schema{}
+}
+
+// component registers the struct once, the name is the type name or package.Type on a collision
+func (spec *Spec) component(structType reflect.Type) string {
+	if name, ok := spec.names[structType]; ok {
+		return name
+	}
+	name := structType.Name()
+	if _, taken := spec.doc.Components.Schemas[name]; taken || name == "" {
+		pkg := structType.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+	spec.names[structType] = name
+	// схема добавляется до обхода полей, чтобы рекурсивные типы ссылались на себя
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	spec.doc.Components.Schemas[name] = schema
+	spec.addFields(schema, structType)
+	return name
+}
+
+// addFields adds the json fields, embedded structs without a json name are flattened
+func (spec *Spec) addFields(schema *Schema, structType reflect.Type) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			spec.addFields(schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := spec.schemaOf(field.Type)
+		if spec.applyRules(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// applyRules describes the validate tag in the schema and reports whether the field is required.
+// Rules after dive apply to the items of a slice.
+func (spec *Spec) applyRules(schema *Schema, tag string) bool {
+	if tag == "" || tag == "-" {
+		return false
+	}
+	rules, itemRules, dive := strings.Cut(tag, ",dive")
+	if dive && schema.Items != nil {
+		spec.applyRules(schema.Items, strings.TrimPrefix(itemRules, ","))
+	}
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid":
+			schema.Format = "uuid"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "gte":
+			setBound(schema, param, true)
+		case "max", "lte":
+			setBound(schema, param, false)
+		case "len":
+			setBound(schema, param, true)
+			setBound(schema, param, false)
+		case "eqfield":
+			schema.Description = "must be equal to " + param
+		default:
+			if described, ok := rulePatterns[name]; ok {
+				if schema.Type == "string" {
+					schema.Pattern = described.Pattern
+				}
+				schema.Description = described.Description
+			} else if enum, ok := spec.enums[name]; ok {
+				schema.Enum = enum
+			}
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, param string, lower bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	count := int(value)
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = &count
+		} else {
+			schema.MaxLength = &count
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &count
+		} else {
+			schema.MaxItems = &count
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &value
+		} else {
+			schema.Maximum = &value
+		}
+	}
+}
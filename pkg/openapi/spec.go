@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	"github.com/gin-gonic/gin"
+	"job_search_platform/pkg/apperr"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+const (
+	// SpecPath serves the document of a service, the gateway collects the documents from it
+	SpecPath        = "/openapi.json"
+	JsonContentType = "application/json"
+	// BearerScheme is the security of the private groups, the access token in Authorization
+	BearerScheme = "bearer"
+	// problemSchema is the component of the error responses
+	problemSchema = "Problem"
+)
+
+// Spec collects the operations while the routes are registered
+type Spec struct {
+	doc   Document
+	names map[reflect.Type]string
+	enums map[string][]string
+}
+
+func NewSpec(title, version string) *Spec {
+	spec := &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    Info{Title: title, Version: version},
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+				SecuritySchemes: map[string]*SecurityScheme{
+					BearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+		names: make(map[reflect.Type]string),
+		enums: make(map[string][]string),
+	}
+	spec.names[reflect.TypeOf(apperr.Problem{})] = problemSchema
+	problem := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	spec.doc.Components.Schemas[problemSchema] = problem
+	spec.addFields(problem, reflect.TypeOf(apperr.Problem{}))
+	return spec
+}
+
+// RegisterEnum describes the enum tag of validation.Validator.RegisterEnum in the schemas
+func (spec *Spec) RegisterEnum(tag string, values ...string) {
+	spec.enums[tag] = values
+}
+
+func (spec *Spec) Document() *Document {
+	return &spec.doc
+}
+
+// Handler serves the document as JSON
+func (spec *Spec) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, spec.Document())
+	}
+}
+
+// Param is a query parameter
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Op describes a handler, the body types come from the Endpoint
+type Op struct {
+	Summary     string
+	Description string
+	Query       []Param
+}
+
+// Group registers the handlers in gin and their operations in the spec
+type Group struct {
+	group   *gin.RouterGroup
+	spec    *Spec
+	tag     string
+	private bool
+}
+
+// Group wraps the router group, operations of a private group require the bearer token
+func (spec *Spec) Group(group *gin.RouterGroup, tag string, private bool) *Group {
+	return &Group{group: group, spec: spec, tag: tag, private: private}
+}
+
+func (group *Group) GET(path string, endpoint Endpoint, op Op) {
+	group.Handle(http.MethodGet, path, endpoint, op)
+}
+
+func (group *Group) POST(path string, endpoint Endpoint, op Op) {
+	group.Handle(http.MethodPost, path, endpoint, op)
+}
+
+func (group *Group) PUT(path string, endpoint Endpoint, op Op) {
+	group.Handle(http.MethodPut, path, endpoint, op)
+}
+
+func (group *Group) DELETE(path string, endpoint Endpoint, op Op) {
+	group.Handle(http.MethodDelete, path, endpoint, op)
+}
+
+func (group *Group) Handle(method, path string, endpoint Endpoint, op Op) {
+	group.group.Handle(method, path, endpoint.handler)
+	fullPath := joinPath(group.group.BasePath(), path)
+	group.spec.add(method, fullPath, group.operation(fullPath, endpoint, op))
+}
+
+func (group *Group) operation(path string, endpoint Endpoint, op Op) *Operation {
+	spec := group.spec
+	operation := &Operation{
+		OperationId: endpoint.name,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        []string{group.tag},
+		Responses: map[string]Response{
+			"200": {
+				Description: "Success",
+				Content:     map[string]MediaType{JsonContentType: {Schema: spec.envelope(endpoint.response)}},
+			},
+			"default": {
+				Description: "Error",
+				Content:     map[string]MediaType{apperr.ProblemContentType: {Schema: SchemaRef(problemSchema)}},
+			},
+		},
+	}
+	for _, name := range pathParams(path) {
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	for _, param := range op.Query {
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name: param.Name, In: "query", Description: param.Description, Required: param.Required,
+			Schema: &Schema{Type: "string"},
+		})
+	}
+	if endpoint.request != noBodyType {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{JsonContentType: {Schema: spec.schemaOf(endpoint.request)}},
+		}
+	}
+	if group.private {
+		operation.Security = []map[string][]string{{BearerScheme: {}}}
+	}
+	return operation
+}
+
+// envelope is the schema of server.Response with the body type, NoBody is null
+func (spec *Spec) envelope(body reflect.Type) *Schema {
+	bodySchema := &Schema{Nullable: true}
+	if body != noBodyType {
+		bodySchema = spec.schemaOf(body)
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":          {Type: "integer", Format: "int32"},
+			"error":         {Type: "string", Nullable: true},
+			"error_message": {Type: "string", Nullable: true},
+			"body":          bodySchema,
+		},
+		Required: []string{"code", "body"},
+	}
+}
+
+func (spec *Spec) add(method, path string, operation *Operation) {
+	path = ginPath.ReplaceAllString(path, "{$1}")
+	item, ok := spec.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		spec.doc.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = operation
+}
+
+// Undocumented returns the gin routes under prefix that were registered past the Group
+func Undocumented(routes gin.RoutesInfo, doc *Document, prefix string) []string {
+	var result []string
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, prefix) {
+			continue
+		}
+		path := ginPath.ReplaceAllString(route.Path, "{$1}")
+		if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+			result = append(result, route.Method+" "+route.Path)
+		}
+	}
+	return result
+}
+
+// ginPath matches the gin parameters, /users/:id is /users/{id} in the spec
+var ginPath = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+func pathParams(path string) []string {
+	var result []string
+	for _, match := range ginPath.FindAllStringSubmatch(path, -1) {
+		result = append(result, match[1])
+	}
+	return result
+}
+
+func joinPath(base, path string) string {
+	if path == "" || path == "/" {
+		return base
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// handlerName is the method name of the handler: (*AuthHandler).SignUpUser-fm is SignUpUser
+func handlerName(handler interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,169 @@
+package password_policy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// breachedPasswords is a bundled list of the most common passwords from public breach corpora
+//
+//go:embed breached_passwords.txt
+var breachedPasswords []byte
+
+const breachedFalsePositiveRate = 0.001
+
+// BreachedChecker reports whether a password is known to be compromised
+type BreachedChecker interface {
+	IsBreached(password string) bool
+}
+
+// BloomBreachedChecker keeps SHA-1 digests of breached passwords in a bloom filter,
+// so the same dataset format as the k-anonymity "Pwned Passwords" ranges can be loaded offline.
+type BloomBreachedChecker struct {
+	filter *bloomFilter
+}
+
+// NewBloomBreachedChecker builds the filter from the bundled list and, if path is not empty,
+// from a file with one SHA-1 hex digest per line (optionally followed by ":count").
+func NewBloomBreachedChecker(path string) (*BloomBreachedChecker, error) {
+	bundled := bytes.Count(breachedPasswords, []byte("\n")) + 1
+	external := 0
+	if path != "" {
+		count, err := countLines(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read breached passwords file: %w", err)
+		}
+		external = count
+	}
+
+	filter := newBloomFilter(bundled+external, breachedFalsePositiveRate)
+	scanner := bufio.NewScanner(bytes.NewReader(breachedPasswords))
+	for scanner.Scan() {
+		password := strings.TrimSpace(scanner.Text())
+		if password == "" {
+			continue
+		}
+		digest := sha1.Sum([]byte(password))
+		filter.add(digest[:])
+	}
+
+	if path != "" {
+		if err := loadDigests(path, filter); err != nil {
+			return nil, fmt.Errorf("cannot load breached passwords file: %w", err)
+		}
+	}
+	return &BloomBreachedChecker{filter: filter}, nil
+}
+
+// IsBreached also checks the lowercased password, most leaked lists differ only by capitalization
+func (checker *BloomBreachedChecker) IsBreached(password string) bool {
+	for _, candidate := range []string{password, strings.ToLower(password)} {
+		digest := sha1.Sum([]byte(candidate))
+		if checker.filter.contains(digest[:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := file.Read(buf)
+		count += bytes.Count(buf[:n], []byte("\n"))
+		if err == io.EOF {
+			return count + 1, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func loadDigests(path string, filter *bloomFilter) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.IndexByte(line, ':'); idx >= 0 {
+			line = line[:idx]
+		}
+		if line == "" {
+			continue
+		}
+		digest, err := hex.DecodeString(line)
+		if err != nil || len(digest) != sha1.Size {
+			return fmt.Errorf("invalid SHA-1 digest %q", line)
+		}
+		filter.add(digest)
+	}
+	return scanner.Err()
+}
+
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+func newBloomFilter(items int, falsePositiveRate float64) *bloomFilter {
+	if items < 1 {
+		items = 1
+	}
+	size := math.Ceil(-float64(items) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Max(1, math.Round(size/float64(items)*math.Ln2))
+	words := (uint64(size) + 63) / 64
+	return &bloomFilter{
+		bits:   make([]uint64, words),
+		size:   words * 64,
+		hashes: uint64(hashes),
+	}
+}
+
+// positions uses double hashing over the first 16 bytes of the digest
+func (filter *bloomFilter) positions(digest []byte, fn func(pos uint64) bool) {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+	for i := uint64(0); i < filter.hashes; i++ {
+		if !fn((h1 + i*h2) % filter.size) {
+			return
+		}
+	}
+}
+
+func (filter *bloomFilter) add(digest []byte) {
+	filter.positions(digest, func(pos uint64) bool {
+		filter.bits[pos/64] |= 1 << (pos % 64)
+		return true
+	})
+}
+
+func (filter *bloomFilter) contains(digest []byte) bool {
+	found := true
+	filter.positions(digest, func(pos uint64) bool {
+		found = filter.bits[pos/64]&(1<<(pos%64)) != 0
+		return found
+	})
+	return found
+}
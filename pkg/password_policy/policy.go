@@ -0,0 +1,135 @@
+package password_policy
+
+import (
+	"errors"
+	"job_search_platform/pkg/config"
+	"job_search_platform/pkg/helpers/server"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrPasswordTooShort             = errors.New("password is too short")
+	ErrPasswordTooLong              = errors.New("password is too long")
+	ErrPasswordNoUpper              = errors.New("password must contain an uppercase letter")
+	ErrPasswordNoLower              = errors.New("password must contain a lowercase letter")
+	ErrPasswordNoDigit              = errors.New("password must contain a digit")
+	ErrPasswordNoSpecial            = errors.New("password must contain a special character")
+	ErrPasswordContainsPersonalInfo = errors.New("password must not contain email or name")
+	ErrPasswordBreached             = errors.New("password has appeared in a data breach")
+	ErrPasswordReused               = errors.New("password was used recently")
+)
+
+// minPersonalInfoLength is the shortest email/name fragment that is checked against the password
+const minPersonalInfoLength = 3
+
+// Policy describes the rules a new password has to satisfy
+type Policy struct {
+	MinLength          int
+	MaxLength          int
+	RequireUpper       bool
+	RequireLower       bool
+	RequireDigit       bool
+	RequireSpecial     bool
+	ForbidPersonalInfo bool
+	HistorySize        int
+	breached           BreachedChecker
+}
+
+func NewPolicy(config config.PasswordPolicy) (*Policy, error) {
+	policy := &Policy{
+		MinLength:          config.PasswordMinLength,
+		MaxLength:          config.PasswordMaxLength,
+		RequireUpper:       config.PasswordRequireUpper,
+		RequireLower:       config.PasswordRequireLower,
+		RequireDigit:       config.PasswordRequireDigit,
+		RequireSpecial:     config.PasswordRequireSpecial,
+		ForbidPersonalInfo: config.PasswordForbidPersonalInfo,
+		HistorySize:        config.PasswordHistorySize,
+	}
+	if config.PasswordCheckBreached {
+		checker, err := NewBloomBreachedChecker(config.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = checker
+	}
+	return policy, nil
+}
+
+// Validate checks the password against the policy. personalInfo holds values
+// (email, first name, last name) that must not appear inside the password.
+func (policy *Policy) Validate(password string, personalInfo ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		return ErrPasswordTooShort
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return ErrPasswordTooLong
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSpecial = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		return ErrPasswordNoUpper
+	}
+	if policy.RequireLower && !hasLower {
+		return ErrPasswordNoLower
+	}
+	if policy.RequireDigit && !hasDigit {
+		return ErrPasswordNoDigit
+	}
+	if policy.RequireSpecial && !hasSpecial {
+		return ErrPasswordNoSpecial
+	}
+
+	if policy.ForbidPersonalInfo && containsPersonalInfo(password, personalInfo) {
+		return ErrPasswordContainsPersonalInfo
+	}
+	if policy.breached != nil && policy.breached.IsBreached(password) {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
+func (policy *Policy) GetErrorCode(err error) (errorCode int32) {
+	switch {
+	case errors.Is(err, ErrPasswordBreached):
+		return server.PASSWORD_BREACHED_ERR_CODE
+	case errors.Is(err, ErrPasswordReused):
+		return server.PASSWORD_REUSED_ERR_CODE
+	case err != nil:
+		return server.PASSWORD_POLICY_ERR_CODE
+	}
+	return server.SUCCESS_CODE
+}
+
+func containsPersonalInfo(password string, personalInfo []string) bool {
+	lowered := strings.ToLower(password)
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		// для email проверяем только имя ящика
+		if at := strings.IndexByte(info, '@'); at >= 0 {
+			info = info[:at]
+		}
+		if utf8.RuneCountInString(info) < minPersonalInfoLength {
+			continue
+		}
+		if strings.Contains(lowered, info) {
+			return true
+		}
+	}
+	return false
+}
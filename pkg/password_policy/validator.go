@@ -0,0 +1,44 @@
+package password_policy
+
+import (
+	"github.com/go-playground/validator/v10"
+	"reflect"
+)
+
+const ValidationTag = "password"
+
+// personalInfoFields are the sibling fields whose values must not appear in the password
+var personalInfoFields = []string{"Email", "FirstName", "LastName"}
+
+// registrar is *validator.Validate or *validation.Validator
+type registrar interface {
+	RegisterValidation(tag string, fn validator.Func, callValidationEvenIfNull ...bool) error
+}
+
+// RegisterValidation registers the "password" tag so request structs
+// can declare `validate:"required,password"`.
+func RegisterValidation(validate registrar, policy *Policy) error {
+	return validate.RegisterValidation(ValidationTag, func(fl validator.FieldLevel) bool {
+		return policy.Validate(fl.Field().String(), personalInfo(fl.Parent())...) == nil
+	})
+}
+
+func personalInfo(parent reflect.Value) []string {
+	for parent.Kind() == reflect.Ptr {
+		if parent.IsNil() {
+			return nil
+		}
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return nil
+	}
+	var result []string
+	for _, name := range personalInfoFields {
+		field := parent.FieldByName(name)
+		if field.IsValid() && field.Kind() == reflect.String {
+			result = append(result, field.String())
+		}
+	}
+	return result
+}
@@ -2,8 +2,12 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/codes"
 	"job_search_platform/pkg/entities/common"
+	"job_search_platform/pkg/tracing"
 )
 
 type TaskDistributor interface {
@@ -12,6 +16,11 @@ type TaskDistributor interface {
 		payload *common.PayloadSendVerifyEmail,
 		opts ...asynq.Option,
 	) error
+	DistributeTaskSendLoginLink(
+		ctx context.Context,
+		payload *common.PayloadSendLoginLink,
+		opts ...asynq.Option,
+	) error
 }
 
 type RedisTaskDistributor struct {
@@ -24,3 +33,33 @@ func NewRedisTaskDistributor(redisOpt asynq.RedisClientOpt) TaskDistributor {
 		client: client,
 	}
 }
+
+// tracedPayload is a task payload with common.TaskMetadata
+type tracedPayload interface {
+	SetTraceContext(carrier map[string]string)
+}
+
+// enqueue stores the trace context of the request in the payload and enqueues the task
+func (distributor *RedisTaskDistributor) enqueue(
+	ctx context.Context,
+	taskType string,
+	payload tracedPayload,
+	opts ...asynq.Option,
+) (*asynq.Task, *asynq.TaskInfo, error) {
+	ctx, span, carrier := tracing.StartEnqueue(ctx, taskType)
+	defer span.End()
+	payload.SetTraceContext(carrier)
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+	task := asynq.NewTask(taskType, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, nil, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+	return task, info, nil
+}
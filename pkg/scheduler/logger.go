@@ -3,22 +3,26 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type Logger struct{}
+// Logger writes the asynq and go-redis messages to the service logger
+type Logger struct {
+	logger zerolog.Logger
+}
 
-func NewLogger() *Logger {
-	return &Logger{}
+func NewLogger(logger zerolog.Logger) *Logger {
+	return &Logger{logger: logger.With().Str("component", "asynq").Logger()}
 }
 
 func (logger *Logger) Print(level zerolog.Level, args ...interface{}) {
-	log.WithLevel(level).Msg(fmt.Sprint(args...))
+	logger.logger.WithLevel(level).Msg(fmt.Sprint(args...))
 }
 
 func (logger *Logger) Printf(ctx context.Context, format string, v ...interface{}) {
-	log.WithLevel(zerolog.DebugLevel).Msgf(format, v...)
+	logger.logger.WithLevel(zerolog.DebugLevel).Msgf(format, v...)
 }
 
 func (logger *Logger) Debug(args ...interface{}) {
@@ -40,3 +44,20 @@ func (logger *Logger) Error(args ...interface{}) {
 func (logger *Logger) Fatal(args ...interface{}) {
 	logger.Print(zerolog.FatalLevel, args...)
 }
+
+// TaskLogger stores a child of base with the task type, id and trace id in the task context,
+// the task handlers log with log.Ctx(ctx)
+func TaskLogger(base zerolog.Logger) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			loggerCtx := base.With().Str("task_type", task.Type())
+			if taskId, ok := asynq.GetTaskID(ctx); ok {
+				loggerCtx = loggerCtx.Str("task_id", taskId)
+			}
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+				loggerCtx = loggerCtx.Str("trace_id", spanContext.TraceID().String())
+			}
+			return next.ProcessTask(loggerCtx.Logger().WithContext(ctx), task)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"job_search_platform/pkg/mail_sender"
+	"job_search_platform/pkg/notifications"
+	"net/url"
+)
+
+const unsubscribePath = "/api/v1/users/public/unsubscribe"
+
+// sendEmail sends a message of the given category to a single recipient. Non-security messages are
+// skipped when the user has switched the category off and carry a one-click List-Unsubscribe header.
+func (processor *RedisTaskProcessor) sendEmail(
+	ctx context.Context,
+	category string,
+	subject string,
+	content string,
+	to string,
+) error {
+	var opts []mail_sender.EmailOption
+	if !notifications.IsMandatory(category) {
+		enabled, err := processor.preferences.IsEnabled(ctx, to, notifications.ChannelEmail, category)
+		if err != nil {
+			return fmt.Errorf("failed to check notification preferences: %w", err)
+		}
+		if !enabled {
+			log.Ctx(ctx).Info().Str("email", to).Str("category", category).Msg("email skipped by notification preferences")
+			return nil
+		}
+
+		unsubscribeUrl, err := processor.unsubscribeUrl(to, category)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, mail_sender.WithListUnsubscribe(unsubscribeUrl))
+	}
+	return processor.mailer.SendEmail(subject, content, []string{to}, nil, nil, nil, opts...)
+}
+
+func (processor *RedisTaskProcessor) unsubscribeUrl(email string, category string) (string, error) {
+	token, err := processor.unsubscribeSigner.CreateToken(notifications.UnsubscribeClaims{
+		Email:    email,
+		Channel:  notifications.ChannelEmail,
+		Category: category,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create unsubscribe token: %w", err)
+	}
+	return fmt.Sprintf("%s%s?token=%s", processor.config.HTTPClientAddress, unsubscribePath, url.QueryEscape(token)), nil
+}
@@ -6,15 +6,18 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/sync/errgroup"
 	"job_search_platform/pkg/config"
 	"job_search_platform/pkg/mail_sender"
+	"job_search_platform/pkg/metrics"
+	"job_search_platform/pkg/notifications"
+	"job_search_platform/pkg/tracing"
 )
 
 type TaskProcessor interface {
 	Start() error
 	Shutdown()
 	ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendLoginLink(ctx context.Context, task *asynq.Task) error
 }
 
 const (
@@ -23,14 +26,24 @@ const (
 )
 
 type RedisTaskProcessor struct {
-	server *asynq.Server
-	config config.Config
-	mailer mail_sender.EmailSender
+	server            *asynq.Server
+	config            config.UsersMrc
+	mailer            mail_sender.EmailSender
+	preferences       notifications.PreferencesChecker
+	unsubscribeSigner *notifications.UnsubscribeSigner
+	logger            zerolog.Logger
 }
 
-func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, mailer mail_sender.EmailSender, config config.Config) TaskProcessor {
-	logger := NewLogger()
-	redis.SetLogger(logger)
+func NewRedisTaskProcessor(
+	redisOpt asynq.RedisClientOpt,
+	mailer mail_sender.EmailSender,
+	config config.UsersMrc,
+	preferences notifications.PreferencesChecker,
+	unsubscribeSigner *notifications.UnsubscribeSigner,
+	logger zerolog.Logger,
+) TaskProcessor {
+	asynqLogger := NewLogger(logger)
+	redis.SetLogger(asynqLogger)
 
 	server := asynq.NewServer(
 		redisOpt,
@@ -40,24 +53,29 @@ func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, mailer mail_sender.Ema
 				QueueDefault:  5,
 			},
 			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
-				log.Error().Err(err).Str("type", task.Type()).
+				log.Ctx(ctx).Error().Err(err).Str("type", task.Type()).
 					Bytes("payload", task.Payload()).Msg("process task failed")
 			}),
-			Logger: logger,
+			Logger: asynqLogger,
 		},
 	)
 
 	return &RedisTaskProcessor{
-		server: server,
-		mailer: mailer,
-		config: config,
+		server:            server,
+		mailer:            mailer,
+		config:            config,
+		preferences:       preferences,
+		unsubscribeSigner: unsubscribeSigner,
+		logger:            logger,
 	}
 }
 
 func (processor *RedisTaskProcessor) Start() error {
 	mux := asynq.NewServeMux()
+	mux.Use(tracing.TaskMiddleware, TaskLogger(processor.logger), metrics.TaskMiddleware)
 
 	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
+	mux.HandleFunc(TaskSendLoginLink, processor.ProcessTaskSendLoginLink)
 	return processor.server.Start(mux)
 }
 
@@ -65,30 +83,14 @@ func (processor *RedisTaskProcessor) Shutdown() {
 	processor.server.Shutdown()
 }
 
-func RunTaskProcessor(
-	ctx context.Context,
-	waitGroup *errgroup.Group,
-	config config.Config,
+// NewTaskProcessor builds the processor of the users_mrc queues with the gmail sender
+func NewTaskProcessor(
+	config config.UsersMrc,
 	redisOpt asynq.RedisClientOpt,
 	logger zerolog.Logger,
-) error {
+	preferences notifications.PreferencesChecker,
+	unsubscribeSigner *notifications.UnsubscribeSigner,
+) TaskProcessor {
 	mailer := mail_sender.NewGmailSender(config.EmailSenderName, config.EmailSenderAddress, config.EmailSenderPassword)
-	taskProcessor := NewRedisTaskProcessor(redisOpt, mailer, config)
-
-	logger.Info().Msg("start task processor")
-	err := taskProcessor.Start()
-
-	if err != nil {
-		return err
-	}
-
-	waitGroup.Go(func() error {
-		<-ctx.Done()
-		logger.Info().Msg("graceful shutdown task processor")
-
-		taskProcessor.Shutdown()
-		logger.Info().Msg("task processor is stopped")
-		return nil
-	})
-	return nil
+	return NewRedisTaskProcessor(redisOpt, mailer, config, preferences, unsubscribeSigner, logger)
 }
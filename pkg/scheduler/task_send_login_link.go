@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"job_search_platform/pkg/entities/common"
+	"job_search_platform/pkg/i18n"
+	"job_search_platform/pkg/notifications"
+	"net/url"
+	"time"
+)
+
+const TaskSendLoginLink = "task:send_login_link"
+
+func (distributor *RedisTaskDistributor) DistributeTaskSendLoginLink(
+	ctx context.Context,
+	payload *common.PayloadSendLoginLink,
+	opts ...asynq.Option,
+) error {
+	task, info, err := distributor.enqueue(ctx, TaskSendLoginLink, payload, opts...)
+	if err != nil {
+		return err
+	}
+
+	// токен в логи не пишем
+	log.Ctx(ctx).Info().Str("type", task.Type()).Str("email", payload.Email).
+		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).Msg("enqueued task")
+	return nil
+}
+
+func (processor *RedisTaskProcessor) ProcessTaskSendLoginLink(ctx context.Context, task *asynq.Task) error {
+	var err error
+	var payload common.PayloadSendLoginLink
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+	// после истечения ссылки письмо уже бесполезно
+	if time.Now().After(payload.ExpiresAt) {
+		return fmt.Errorf("login link expired: %w", asynq.SkipRetry)
+	}
+
+	loginUrl := fmt.Sprintf("%s%s?token=%s",
+		processor.config.HTTPClientAddress, processor.config.MagicLinkPath, url.QueryEscape(payload.Token))
+	subject, content, err := i18n.RenderEmail(payload.LangCode, i18n.EmailLoginLink, map[string]interface{}{
+		"FullName":  fmt.Sprintf("%s %s", payload.FirstName, payload.LastName),
+		"LoginUrl":  loginUrl,
+		"ExpiresAt": payload.ExpiresAt.Format(i18n.T(payload.LangCode, "format.datetime")),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render login link: %v: %w", err, asynq.SkipRetry)
+	}
+	err = processor.sendEmail(ctx, notifications.CategorySecurity, subject, content, payload.Email)
+	if err != nil {
+		return fmt.Errorf("failed to send login link: %w", err)
+	}
+	log.Ctx(ctx).Info().Str("type", task.Type()).Str("email", payload.Email).Msg("processed task")
+	return nil
+}
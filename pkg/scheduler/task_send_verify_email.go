@@ -7,6 +7,8 @@ import (
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog/log"
 	"job_search_platform/pkg/entities/common"
+	"job_search_platform/pkg/i18n"
+	"job_search_platform/pkg/notifications"
 )
 
 const TaskSendVerifyEmail = "task:send_verify_email"
@@ -16,17 +18,12 @@ func (distributor *RedisTaskDistributor) DistributeTaskSendVerifyEmail(
 	payload *common.PayloadSendVerifyEmail,
 	opts ...asynq.Option,
 ) error {
-	jsonPayload, err := json.Marshal(payload)
+	task, info, err := distributor.enqueue(ctx, TaskSendVerifyEmail, payload, opts...)
 	if err != nil {
-		return fmt.Errorf("failed to marshal task payload: %w", err)
-	}
-	task := asynq.NewTask(TaskSendVerifyEmail, jsonPayload, opts...)
-	info, err := distributor.client.EnqueueContext(ctx, task)
-	if err != nil {
-		return fmt.Errorf("failed to enqueue task: %w", err)
+		return err
 	}
 
-	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+	log.Ctx(ctx).Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
 		Str("queue", info.Queue).Int("max_retry", info.MaxRetry).Msg("enqueued task")
 	return nil
 }
@@ -38,22 +35,21 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
 	}
 
-	fullName := fmt.Sprintf("%s %s", payload.FirstName, payload.LastName)
-
-	subject := "Добро пожаловать на лучший проект"
 	// TODO: replace this URL with an environment variable that points to a front-end page
 	verifyUrl := fmt.Sprintf("%s/api/v1/auth/public/email-confirmation?token=%s",
 		processor.config.HTTPClientAddress, payload.JWTToken)
-	content := fmt.Sprintf(`Здравствуйне, %s!<br/>
-	Спасибо за регистрацию!<br/>
-	Пожалуйста <a href="%s">нажмите</a> для подтверждения почты.<br/>
-	`, fullName, verifyUrl)
-	to := []string{payload.Email}
-	err = processor.mailer.SendEmail(subject, content, to, nil, nil, nil)
+	subject, content, err := i18n.RenderEmail(payload.LangCode, i18n.EmailVerifyEmail, map[string]interface{}{
+		"FullName":  fmt.Sprintf("%s %s", payload.FirstName, payload.LastName),
+		"VerifyUrl": verifyUrl,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render verify email: %v: %w", err, asynq.SkipRetry)
+	}
+	err = processor.sendEmail(ctx, notifications.CategorySecurity, subject, content, payload.Email)
 	if err != nil {
 		return fmt.Errorf("failed to send verify email: %w", err)
 	}
-	log.Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
+	log.Ctx(ctx).Info().Str("type", task.Type()).Bytes("payload", task.Payload()).
 		Str("email", payload.Email).Msg("processed task")
 	return nil
 }
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// taskMetadata reads common.TaskMetadata embedded in the task payloads, asynq tasks have no headers
+type taskMetadata struct {
+	TraceContext map[string]string `json:"trace_context"`
+}
+
+// StartEnqueue starts a producer span and returns the trace context to store in the task payload
+func StartEnqueue(ctx context.Context, taskType string) (context.Context, trace.Span, map[string]string) {
+	ctx, span := Tracer().Start(ctx, "enqueue "+taskType,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.system", "asynq"), attribute.String("asynq.task_type", taskType)),
+	)
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return ctx, span, carrier
+}
+
+// TaskMiddleware continues the trace stored in the payload and starts a consumer span for the task
+func TaskMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		var metadata taskMetadata
+		// задачи без метаданных начинают новую трассу
+		_ = json.Unmarshal(task.Payload(), &metadata)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(metadata.TraceContext))
+		ctx, span := Tracer().Start(ctx, "process "+task.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String("messaging.system", "asynq"), attribute.String("asynq.task_type", task.Type())),
+		)
+		defer span.End()
+		err := next.ProcessTask(ctx, task)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	})
+}
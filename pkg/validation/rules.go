@@ -0,0 +1,36 @@
+package validation
+
+import (
+	"github.com/go-playground/validator/v10"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// e164 is the number without "+": country code and subscriber number, up to 15 digits
+var e164 = regexp.MustCompile(`^[1-9][0-9]{6,14}$`)
+
+// phone accepts "+77011234567" in strings and 77011234567 in numbers, JSON numbers are decoded as float64
+func phone(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		value := field.String()
+		if len(value) == 0 || value[0] != '+' {
+			return false
+		}
+		return e164.MatchString(value[1:])
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e164.MatchString(strconv.FormatInt(field.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return e164.MatchString(strconv.FormatUint(field.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		value := field.Float()
+		if value != math.Trunc(value) || value <= 0 || value > math.MaxInt64 {
+			return false
+		}
+		return e164.MatchString(strconv.FormatInt(int64(value), 10))
+	}
+	return false
+}
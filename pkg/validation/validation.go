@@ -0,0 +1,185 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"job_search_platform/pkg/apperr"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TagName is the struct tag with the rules, the same in the services and the gateway
+const TagName = "validate"
+
+// Custom rules
+const (
+	// PhoneTag is an E.164 number: the string form with "+" or the digits as an integer
+	PhoneTag = "phone"
+	// CountryTag is an ISO 3166-1 alpha-2 code
+	CountryTag = "country_code"
+)
+
+// FailedCode is the code of the domain error with the field errors
+const FailedCode = "validation_failed"
+
+// embeddedName names the embedded structs in the namespace, such segments are dropped from the field path
+const embeddedName = "~"
+
+// Validator checks the `validate` tags and reports every failed field by its json path.
+// It implements binding.StructValidator, so gin checks the same rules in ShouldBindJSON.
+type Validator struct {
+	validate *validator.Validate
+	// enums are the aliases from RegisterEnum, their errors are reported as oneof
+	enums map[string]bool
+}
+
+func New() *Validator {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.SetTagName(TagName)
+	validate.RegisterTagNameFunc(jsonName)
+	validate.RegisterAlias(CountryTag, "iso3166_1_alpha2")
+	// ошибка возможна только при пустом теге или nil функции
+	if err := validate.RegisterValidation(PhoneTag, phone); err != nil {
+		panic(err)
+	}
+	return &Validator{validate: validate, enums: make(map[string]bool)}
+}
+
+// RegisterValidation adds a custom rule, the signature matches *validator.Validate
+func (v *Validator) RegisterValidation(tag string, fn validator.Func, callValidationEvenIfNull ...bool) error {
+	return v.validate.RegisterValidation(tag, fn, callValidationEvenIfNull...)
+}
+
+// RegisterEnum adds the tag that accepts only the values, e.g. the values of a database enum
+func (v *Validator) RegisterEnum(tag string, values ...string) {
+	v.validate.RegisterAlias(tag, "oneof="+strings.Join(values, " "))
+	v.enums[tag] = true
+}
+
+// Struct checks the rules of the struct, nil or *apperr.Error with the field errors is returned
+func (v *Validator) Struct(value interface{}) error {
+	err := v.validate.Struct(value)
+	if err == nil {
+		return nil
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return apperr.Internal("validation_error", err)
+	}
+	fields := make([]apperr.FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fields = append(fields, v.fieldError(fieldPath(fieldError.Namespace()), fieldError))
+	}
+	return apperr.Validation(FailedCode, fields...)
+}
+
+// ValidateStruct is called by gin after binding, pointers and slices of structs are checked too
+func (v *Validator) ValidateStruct(obj interface{}) error {
+	if obj == nil {
+		return nil
+	}
+	value := reflect.ValueOf(obj)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return v.ValidateStruct(value.Elem().Interface())
+	case reflect.Struct:
+		return v.Struct(obj)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := v.ValidateStruct(value.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Engine returns *validator.Validate, part of binding.StructValidator
+func (v *Validator) Engine() interface{} {
+	return v.validate
+}
+
+// Map checks decoded JSON by the rules of its top level fields, for the bodies that have no struct
+// (the gateway). Rules referencing other fields, like eqfield, are not supported here.
+func (v *Validator) Map(data map[string]interface{}, rules map[string]string) (err error) {
+	// неизвестное правило в validator вызывает панику
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = apperr.Internal("validation_error", fmt.Errorf("%v", recovered))
+		}
+	}()
+	var fields []apperr.FieldError
+	for field, rule := range rules {
+		err := v.validate.Var(data[field], rule)
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			for _, fieldError := range validationErrors {
+				fields = append(fields, v.fieldError(field, fieldError))
+			}
+		} else if err != nil {
+			return apperr.Internal("validation_error", err)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return apperr.Validation(FailedCode, fields...)
+}
+
+// CheckRules reports the rules that use unknown tags, so they are rejected before the first request
+func (v *Validator) CheckRules(rules map[string]string) error {
+	for field, rule := range rules {
+		if err := v.checkRule(rule); err != nil {
+			return fmt.Errorf("field %q: %w", field, err)
+		}
+	}
+	return nil
+}
+
+func (v *Validator) checkRule(rule string) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("invalid rule %q: %v", rule, recovered)
+		}
+	}()
+	v.validate.Var(nil, rule)
+	return nil
+}
+
+// fieldError keeps the failed tag as the code, the message is localized with the response
+func (v *Validator) fieldError(field string, fieldError validator.FieldError) apperr.FieldError {
+	code := fieldError.Tag()
+	if v.enums[code] {
+		code = "oneof"
+	}
+	return apperr.FieldError{Field: field, Code: code, Param: fieldError.Param()}
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" && field.Anonymous {
+		return embeddedName
+	}
+	return name
+}
+
+// fieldPath drops the root struct and the embedded structs: CreateUser.~.email is email
+func fieldPath(namespace string) string {
+	segments := strings.Split(namespace, ".")
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments[1:] {
+		if segment != embeddedName {
+			path = append(path, segment)
+		}
+	}
+	return strings.Join(path, ".")
+}